@@ -0,0 +1,169 @@
+package daikin
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Protocol is the protocol family spoken by the Wifi adapter.
+type Protocol int
+
+// The supported protocol families.
+const (
+	// ProtocolBRP is the key=value protocol of the BRP072A42 and
+	// BRP069 adapters below /common and /aircon.
+	ProtocolBRP Protocol = 0
+	// ProtocolAirBase is the key=value protocol of the SKYFi and
+	// BRP15B61 AirBase adapters below /skyfi.
+	ProtocolAirBase Protocol = 1
+	// ProtocolDsiot is the JSON protocol of the BRP084 adapters and
+	// firmware 2.8 and newer below /dsiot. Adapters with firmware 2.8
+	// may still answer /common/basic_info, but not /aircon.
+	ProtocolDsiot Protocol = 2
+)
+
+var protocolMap = map[Protocol]string{
+	ProtocolBRP:     "BRP",
+	ProtocolAirBase: "AirBase",
+	ProtocolDsiot:   "dsiot",
+}
+
+func (p *Protocol) String() string {
+	v, ok := protocolMap[*p]
+	if !ok {
+		return "Unknown Protocol"
+	}
+	return v
+}
+
+// MarshalText returns "brp", "airbase" or "dsiot", unknown values as
+// number.
+func (p Protocol) MarshalText() ([]byte, error) {
+	if v, ok := protocolMap[p]; ok {
+		return []byte(strings.ToLower(v)), nil
+	}
+	return []byte(strconv.Itoa(int(p))), nil
+}
+
+// UnmarshalText accepts the protocol name or the numeric value.
+func (p *Protocol) UnmarshalText(text []byte) error {
+	for k, v := range protocolMap {
+		if strings.EqualFold(v, string(text)) {
+			*p = k
+			return nil
+		}
+	}
+	n, err := strconv.Atoi(string(text))
+	if err != nil {
+		return fmt.Errorf("unknown protocol: %s", string(text))
+	}
+	if _, ok := protocolMap[Protocol(n)]; !ok {
+		return fmt.Errorf("unknown protocol: %s", string(text))
+	}
+	*p = Protocol(n)
+	return nil
+}
+
+// UnmarshalJSON accepts a string or a number.
+func (p *Protocol) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, p)
+}
+
+const airbasePrefix = "/skyfi"
+
+// AirBase mode values and their BRP equivalents.
+var airbaseModeMap = map[string]string{
+	"0": "6", // Fan
+	"1": "4", // Heat
+	"2": "3", // Cool
+	"3": "0", // Auto
+	"7": "2", // Dehumidify
+}
+
+var brpModeMap = map[string]string{
+	"0": "3",
+	"1": "3",
+	"7": "3",
+	"2": "7",
+	"3": "2",
+	"4": "1",
+	"6": "0",
+}
+
+// AirBase only knows low, mid and high, auto is a separate flag.
+var airbaseFanMap = map[string]string{
+	"1": "3",
+	"3": "5",
+	"5": "7",
+}
+
+var brpFanMap = map[string]string{
+	"B": "1",
+	"3": "1",
+	"4": "1",
+	"5": "3",
+	"6": "5",
+	"7": "5",
+}
+
+// airbaseFromWire translates the values of an AirBase reply into the
+// BRP representation understood by the populate methods.
+func airbaseFromWire(values map[string]string) map[string]string {
+	ret := make(map[string]string, len(values))
+	for k, v := range values {
+		switch k {
+		case "mode":
+			if m, ok := airbaseModeMap[v]; ok {
+				v = m
+			}
+		case "f_rate":
+			if f, ok := airbaseFanMap[strings.TrimSuffix(v, "a")]; ok {
+				v = f
+			}
+			if values["f_auto"] == "1" || strings.HasSuffix(values["f_rate"], "a") {
+				v = string(FanAuto)
+			}
+		case "htemp", "otemp", "stemp":
+			// AirBase reports missing sensors as "-"
+			if v == "-" {
+				v = "--"
+			}
+		}
+		ret[k] = v
+	}
+	return ret
+}
+
+// airbaseToWire translates a BRP query string for set_control_info into
+// the AirBase representation, which requires the fan auto, airside and
+// local password parameters in addition.
+func airbaseToWire(query string, lpw string) string {
+	var params []string
+	fanAuto := "0"
+	for _, p := range strings.Split(query, "&") {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		k, v := kv[0], kv[1]
+		switch k {
+		case "mode":
+			if m, ok := brpModeMap[v]; ok {
+				v = m
+			}
+		case "f_rate":
+			if v == string(FanAuto) {
+				fanAuto = "1"
+				v = "3"
+			} else if f, ok := brpFanMap[v]; ok {
+				v = f
+			}
+		}
+		params = append(params, k+"="+v)
+	}
+	params = append(params, "f_auto="+fanAuto, "f_airside=0",
+		"lpw="+url.QueryEscape(lpw))
+	return strings.Join(params, "&")
+}
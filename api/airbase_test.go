@@ -0,0 +1,57 @@
+package daikin_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+func TestBRP069BasicInfo(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+	// BRP069 adapters report adp_kind=3 like AirBase adapters
+	setValues(s, "/common/basic_info", seedValues(t, "brp069_basic_info"))
+
+	d := newDaikin(s.Address())
+	st, err := d.Refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Protocol != daikin.ProtocolBRP {
+		t.Errorf("protocol is %s, want BRP", d.Protocol.String())
+	}
+	if st.ControlInfo == nil || st.SensorInfo == nil {
+		t.Fatalf("control or sensor info missing: %+v", st.Snapshot)
+	}
+	if got := st.BasicInfo.Name.String(); got != "Living" {
+		t.Errorf("name is %q, want Living", got)
+	}
+}
+
+func TestAirBase(t *testing.T) {
+	s := daikintest.NewAirBaseServer()
+	defer s.Close()
+
+	d := newDaikin(s.Address())
+	st, err := d.Refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Protocol != daikin.ProtocolAirBase {
+		t.Fatalf("protocol is %s, want AirBase", d.Protocol.String())
+	}
+	if st.ControlInfo.Mode != daikin.ModeCool {
+		t.Errorf("mode is %s, want Cool", st.ControlInfo.Mode.String())
+	}
+	if st.Zones == nil || len(st.Zones.Zones) != 8 {
+		t.Fatalf("zones: %+v", st.Zones)
+	}
+	if _, err := d.Apply(context.Background(), daikin.WithFan(daikin.FanAuto)); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Get("/skyfi/aircon/get_control_info", "f_auto"); got != "1" {
+		t.Errorf("f_auto is %q, want 1", got)
+	}
+}
@@ -0,0 +1,235 @@
+package daikin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Change modifies a setting of the control info, see Apply.
+type Change func(c *ControlInfo) error
+
+// WithPower switches the unit on or off.
+func WithPower(p Power) Change {
+	return func(c *ControlInfo) error {
+		if _, ok := powerMap[p]; !ok {
+			return fmt.Errorf("unknown power value: %d", int(p))
+		}
+		c.Power = p
+		return nil
+	}
+}
+
+// WithMode sets the operating mode.
+func WithMode(m Mode) Change {
+	return func(c *ControlInfo) error {
+		if _, ok := modeMap[m]; !ok {
+			return fmt.Errorf("unknown mode value: %d", int(m))
+		}
+		c.Mode = m
+		return nil
+	}
+}
+
+// WithTemperature sets the target temperature in Celsius.
+func WithTemperature(t float64) Change {
+	return func(c *ControlInfo) error {
+		return c.Temperature.decode("stemp", strconv.FormatFloat(t, 'f', 1, 64))
+	}
+}
+
+// WithHumidity sets the target humidity in percent.
+func WithHumidity(h int) Change {
+	return func(c *ControlInfo) error {
+		return c.Humidity.decode("shum", strconv.Itoa(h))
+	}
+}
+
+// WithFan sets the fan speed.
+func WithFan(f Fan) Change {
+	return func(c *ControlInfo) error {
+		if _, ok := fanMap[f]; !ok {
+			return fmt.Errorf("unknown fan value: %s", string(f))
+		}
+		c.Fan = f
+		return nil
+	}
+}
+
+// WithFanDir sets the fan louvre swing. On units with 3D airflow, the
+// vertical and horizontal louvres are set accordingly.
+func WithFanDir(f FanDir) Change {
+	return func(c *ControlInfo) error {
+		if _, ok := fanDirMap[f]; !ok {
+			return fmt.Errorf("unknown f_dir value: %d", int(f))
+		}
+		c.setFanDir(f)
+		return nil
+	}
+}
+
+// WithSwing sets the vertical and horizontal louvre swing. On units
+// without separate louvre settings, the combined FanDir is set.
+func WithSwing(ud Swing, lr Swing) Change {
+	return func(c *ControlInfo) error {
+		if _, ok := swingMap[ud]; !ok {
+			return fmt.Errorf("unknown f_dir_ud value: %s", string(ud))
+		}
+		if _, ok := swingMap[lr]; !ok {
+			return fmt.Errorf("unknown f_dir_lr value: %s", string(lr))
+		}
+		c.setFanDir(FanDirOf(ud, lr))
+		return nil
+	}
+}
+
+// WithVerticalSwing sets the vertical louvre swing and keeps the
+// horizontal one.
+func WithVerticalSwing(ud Swing) Change {
+	return func(c *ControlInfo) error {
+		_, lr := c.FanDir.Swing()
+		return WithSwing(ud, lr)(c)
+	}
+}
+
+// WithHorizontalSwing sets the horizontal louvre swing and keeps the
+// vertical one.
+func WithHorizontalSwing(lr Swing) Change {
+	return func(c *ControlInfo) error {
+		ud, _ := c.FanDir.Swing()
+		return WithSwing(ud, lr)(c)
+	}
+}
+
+// setFanDir sets FanDir and the separate louvres the unit reported.
+func (c *ControlInfo) setFanDir(f FanDir) {
+	c.FanDir = f
+	ud, lr := f.Swing()
+	if c.FanDirUD.IsSet() {
+		c.FanDirUD = ud
+	}
+	if c.FanDirLR.IsSet() {
+		c.FanDirLR = lr
+	}
+}
+
+// Merge returns a copy of c with the changes applied. If the mode is
+// changed without a new target temperature or humidity, the values the
+// unit remembered for the new mode (dtN and dhN in Extra) are taken.
+// Without them, the target temperature is set to "--" in fan mode, to
+// "M" in dehumidify mode and limited to the default range of the other
+// modes, the target humidity is set to "AUTO" in dehumidify mode.
+func (c *ControlInfo) Merge(changes ...Change) (*ControlInfo, error) {
+	if c == nil {
+		return nil, fmt.Errorf("no control settings")
+	}
+	ci := *c
+	for _, change := range changes {
+		if err := change(&ci); err != nil {
+			return nil, err
+		}
+	}
+	if ci.Mode != c.Mode {
+		k := modeKey(ci.Mode)
+		if ci.Temperature.encode() == c.Temperature.encode() {
+			if v, ok := c.Extra["dt"+k]; !ok || ci.Temperature.decode("stemp", v) != nil {
+				ci.Temperature = modeTemperature(ci.Mode, ci.Temperature)
+			}
+		}
+		if ci.Humidity.encode() == c.Humidity.encode() {
+			if v, ok := c.Extra["dh"+k]; !ok || ci.Humidity.decode("shum", v) != nil {
+				if ci.Mode == ModeDehumidify {
+					ci.Humidity.decode("shum", HumidityAuto)
+				}
+			}
+		}
+	}
+	return &ci, nil
+}
+
+// modeKey returns the suffix of the values the unit remembers per mode,
+// e.g. dt3 and dh3 for cool. Auto uses the values of auto (1).
+func modeKey(m Mode) string {
+	if m == ModeAuto {
+		m = ModeAuto1
+	}
+	return strconv.Itoa(int(m))
+}
+
+// modeTemperature returns the target temperature t of another mode for
+// mode, if the unit did not remember one.
+func modeTemperature(mode Mode, t Temperature) Temperature {
+	switch mode {
+	case ModeFan:
+		t.decode("stemp", TemperatureNone)
+		return t
+	case ModeDehumidify:
+		t.decode("stemp", TemperatureModeDefault)
+		return t
+	}
+	r, ok := DefaultSetpoints[mode]
+	if !ok || !t.IsSet() {
+		return t
+	}
+	v := math.Min(math.Max(t.Value(), r.Min), r.Max)
+	t.decode("stemp", strconv.FormatFloat(v, 'f', 1, 64))
+	return t
+}
+
+// Apply reads the current control info of the unit, merges the changes,
+// validates the result and writes it to the unit. It returns the
+// control info written.
+func (d *Daikin) Apply(ctx context.Context, changes ...Change) (*ControlInfo, error) {
+	if !d.present(SectionBasicInfo) {
+		// detect the protocol of the unit
+		if err := d.getBasicInfo(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if !d.present(SectionModelInfo) {
+		if err := d.getModelInfo(ctx); err != nil && !errors.Is(err, ErrNotSupported) {
+			return nil, err
+		}
+	}
+	d.Invalidate(SectionControlInfo)
+	if err := d.getControlInfo(ctx); err != nil {
+		return nil, err
+	}
+	s := d.Snapshot()
+	ci, err := s.ControlInfo.Merge(changes...)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.SetControl(ctx, ci); err != nil {
+		return nil, err
+	}
+	return ci, nil
+}
+
+// ApplyTo applies the changes to dev like Daikin.Apply. Devices without
+// an Apply method are refreshed before the changes are merged into the
+// control info of the snapshot and validated.
+func ApplyTo(ctx context.Context, dev Device, changes ...Change) (*ControlInfo, error) {
+	if a, ok := dev.(interface {
+		Apply(context.Context, ...Change) (*ControlInfo, error)
+	}); ok {
+		return a.Apply(ctx, changes...)
+	}
+	s, err := dev.Refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ci, err := s.ControlInfo.Merge(changes...)
+	if err != nil {
+		return nil, err
+	}
+	if err := ci.ValidateChange(s.ControlInfo, s.ModelInfo); err != nil {
+		return nil, err
+	}
+	if err := dev.SetControl(ctx, ci); err != nil {
+		return nil, err
+	}
+	return ci, nil
+}
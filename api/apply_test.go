@@ -0,0 +1,102 @@
+package daikin_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+// newBRP069 returns a fake adapter with the control info of a BRP069
+// adapter, which remembers the settings per mode, in the given mode.
+func newBRP069(t *testing.T, values map[string]string) *daikintest.Server {
+	s := daikintest.NewServer()
+	setValues(s, "/aircon/get_control_info", seedValues(t, "brp069_control_info"))
+	setValues(s, "/aircon/get_control_info", values)
+	return s
+}
+
+func TestMergeModeChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]string
+		mode   daikin.Mode
+		stemp  string
+		shum   string
+	}{
+		{"fan to cool", map[string]string{"mode": "6", "stemp": "--"}, daikin.ModeCool, "25.0", "0"},
+		{"heat 12 to cool", map[string]string{"mode": "4", "stemp": "12.0"}, daikin.ModeCool, "25.0", "0"},
+		{"heat to dehumidify", map[string]string{"mode": "4"}, daikin.ModeDehumidify, "M", "50"},
+		{"cool to fan", map[string]string{"mode": "3"}, daikin.ModeFan, "--", "0"},
+		{"cool to heat", map[string]string{"mode": "3", "stemp": "25.0"}, daikin.ModeHeat, "21.0", "0"},
+	}
+	for _, tt := range tests {
+		s := newBRP069(t, tt.values)
+		d := newDaikin(s.Address())
+		ci, err := d.Apply(context.Background(), daikin.WithMode(tt.mode))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else {
+			if ci.Mode != tt.mode {
+				t.Errorf("%s: mode is %s", tt.name, ci.Mode.String())
+			}
+			if v := s.Get("/aircon/get_control_info", "stemp"); v != tt.stemp {
+				t.Errorf("%s: stemp is %q, want %q", tt.name, v, tt.stemp)
+			}
+			if v := s.Get("/aircon/get_control_info", "shum"); v != tt.shum {
+				t.Errorf("%s: shum is %q, want %q", tt.name, v, tt.shum)
+			}
+		}
+		s.Close()
+	}
+}
+
+func TestMergeWithoutRememberedValues(t *testing.T) {
+	c := &daikin.ControlInfo{Mode: daikin.ModeHeat}
+	c.Temperature.Set("12.0")
+	ci, err := c.Merge(daikin.WithMode(daikin.ModeCool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ci.Temperature.Value(); v != 18 {
+		t.Errorf("temperature is %v, want 18 limited to the cool range", v)
+	}
+
+	ci, err = c.Merge(daikin.WithMode(daikin.ModeDehumidify))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ci.Temperature.IsModeDefault() || !ci.Humidity.IsAuto() {
+		t.Errorf("dehumidify: temperature %s, humidity %s", ci.Temperature.String(), ci.Humidity.String())
+	}
+
+	// an explicit temperature is kept
+	ci, err = c.Merge(daikin.WithMode(daikin.ModeCool), daikin.WithTemperature(22))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ci.Temperature.Value(); v != 22 {
+		t.Errorf("temperature is %v, want 22", v)
+	}
+}
+
+func TestApplyTo(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+
+	d := newDaikin(s.Address())
+	ci, err := daikin.ApplyTo(context.Background(), d, daikin.WithPower(daikin.PowerOn), daikin.WithFan(daikin.Fan3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ci.Power != daikin.PowerOn || ci.Fan != daikin.Fan3 {
+		t.Errorf("control info: %s", ci.String())
+	}
+	if got := s.Get("/aircon/get_control_info", "f_rate"); got != "5" {
+		t.Errorf("f_rate is %q, want 5", got)
+	}
+	if _, err := daikin.ApplyTo(context.Background(), d, daikin.WithMode(daikin.Mode(5))); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
@@ -0,0 +1,159 @@
+package daikin
+
+import (
+	"sync"
+	"time"
+)
+
+// Section is a part of the state of a unit, which is fetched with one
+// request.
+type Section int
+
+const (
+	SectionBasicInfo Section = iota
+	SectionControlInfo
+	SectionSensorInfo
+	SectionPowerInfo
+	SectionZones
+	SectionModelInfo
+	numSections
+)
+
+var sectionNames = []string{"basic_info", "control_info", "sensor_info", "power_info", "zones", "model_info"}
+
+// Optional returns true for the sections not every unit has: the power
+// info, the zones and the model info.
+func (s Section) Optional() bool {
+	return s == SectionPowerInfo || s == SectionZones || s == SectionModelInfo
+}
+
+func (s Section) String() string {
+	if s < 0 || s >= numSections {
+		return "unknown"
+	}
+	return sectionNames[s]
+}
+
+// CacheTTL configures how long the sections of the state are reused
+// before they are fetched again. A TTL of zero disables caching of the
+// section. The model info is fetched only once.
+type CacheTTL struct {
+	BasicInfo   time.Duration
+	ControlInfo time.Duration
+	SensorInfo  time.Duration
+	PowerInfo   time.Duration
+	Zones       time.Duration
+}
+
+// DefaultCacheTTL refetches the basic info hourly and the power
+// consumption every 5 minutes.
+var DefaultCacheTTL = CacheTTL{
+	BasicInfo:   time.Hour,
+	ControlInfo: 10 * time.Second,
+	SensorInfo:  10 * time.Second,
+	PowerInfo:   5 * time.Minute,
+	Zones:       10 * time.Second,
+}
+
+func (c *CacheTTL) ttl(s Section) time.Duration {
+	switch s {
+	case SectionBasicInfo:
+		return c.BasicInfo
+	case SectionControlInfo:
+		return c.ControlInfo
+	case SectionSensorInfo:
+		return c.SensorInfo
+	case SectionPowerInfo:
+		return c.PowerInfo
+	case SectionZones:
+		return c.Zones
+	}
+	return 0
+}
+
+// cache records when the sections of a unit were fetched.
+type cache struct {
+	mu      sync.Mutex
+	fetched [numSections]time.Time
+	// updated is when the section was last fetched, it is kept by
+	// Invalidate
+	updated [numSections]time.Time
+}
+
+// cached returns true if the section is present and was fetched less
+// than its TTL ago.
+func (d *Daikin) cached(s Section) bool {
+	if d.Cache == nil || !d.present(s) {
+		return false
+	}
+	age, ok := d.CacheAge(s)
+	return ok && age < d.Cache.ttl(s)
+}
+
+// present returns true if the section has been fetched.
+func (d *Daikin) present(s Section) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	switch s {
+	case SectionBasicInfo:
+		return d.BasicInfo != nil
+	case SectionControlInfo:
+		return d.ControlInfo != nil
+	case SectionSensorInfo:
+		return d.SensorInfo != nil
+	case SectionPowerInfo:
+		return d.PowerInfo != nil
+	case SectionZones:
+		return d.Zones != nil
+	case SectionModelInfo:
+		return d.ModelInfo != nil
+	}
+	return false
+}
+
+// fetched records that the section was just fetched.
+func (d *Daikin) fetched(s Section) {
+	d.cache.mu.Lock()
+	defer d.cache.mu.Unlock()
+	d.cache.fetched[s] = time.Now()
+	d.cache.updated[s] = d.cache.fetched[s]
+}
+
+// updated returns when the sections were last fetched.
+func (d *Daikin) updated() [numSections]time.Time {
+	d.cache.mu.Lock()
+	defer d.cache.mu.Unlock()
+	return d.cache.updated
+}
+
+// CacheAge returns the time since the section was last fetched from the
+// unit. ok is false if the section was not fetched yet or has been
+// invalidated.
+func (d *Daikin) CacheAge(s Section) (age time.Duration, ok bool) {
+	if s < 0 || s >= numSections {
+		return 0, false
+	}
+	d.cache.mu.Lock()
+	defer d.cache.mu.Unlock()
+	if d.cache.fetched[s].IsZero() {
+		return 0, false
+	}
+	return time.Since(d.cache.fetched[s]), true
+}
+
+// Invalidate discards the cached sections, so that they are fetched
+// again by the next call. Without arguments, all sections are
+// invalidated.
+func (d *Daikin) Invalidate(sections ...Section) {
+	d.cache.mu.Lock()
+	defer d.cache.mu.Unlock()
+	if len(sections) == 0 {
+		d.cache.fetched = [numSections]time.Time{}
+		return
+	}
+	for _, s := range sections {
+		if s >= 0 && s < numSections {
+			d.cache.fetched[s] = time.Time{}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package daikin_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+func TestCache(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+
+	d := newDaikin(s.Address())
+	d.Cache = &daikin.CacheTTL{BasicInfo: time.Hour, ControlInfo: time.Hour}
+	ctx := context.Background()
+	if _, err := d.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(s, "/common/basic_info"); n != 1 {
+		t.Errorf("basic info fetched %d times, want once", n)
+	}
+	if n := count(s, "/aircon/get_control_info"); n != 2 {
+		// the first one probes the protocol
+		t.Errorf("control info fetched %d times, want 2", n)
+	}
+	if n := count(s, "/aircon/get_sensor_info"); n != 2 {
+		t.Errorf("sensor info without TTL fetched %d times, want 2", n)
+	}
+	if _, ok := d.CacheAge(daikin.SectionControlInfo); !ok {
+		t.Error("control info not cached")
+	}
+
+	// writes invalidate the control info
+	if _, err := d.Apply(ctx, daikin.WithPower(daikin.PowerOn)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.CacheAge(daikin.SectionControlInfo); ok {
+		t.Error("control info cached after write")
+	}
+	st, err := d.Refresh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.ControlInfo.Power != daikin.PowerOn {
+		t.Errorf("power is %s after write", st.ControlInfo.Power.String())
+	}
+}
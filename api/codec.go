@@ -0,0 +1,167 @@
+package daikin
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The info structs declare the wire key of each field with the daikin
+// struct tag:
+//
+//	Power Power `daikin:"pow"`
+//
+// Fields may be a Parameter, bool ("1" is true) or int. A
+// map[string]string field tagged `daikin:",extra"` receives all keys
+// without a field, so that values unknown to this package stay
+// accessible. Parameter fields tagged `daikin:"key,omitempty"` are only
+// written if they have a value, for keys only some units know. Adding a
+// value is a single field declaration.
+
+const tagName = "daikin"
+
+type fieldKind int
+
+const (
+	kindParameter fieldKind = iota
+	kindBool
+	kindInt
+)
+
+type codecField struct {
+	key   string
+	index int
+	kind  fieldKind
+	// omitEmpty skips the field in set requests if it has no value
+	omitEmpty bool
+}
+
+type codecTable struct {
+	fields []codecField
+	byKey  map[string]int
+	// index of the extra field, -1 if none
+	extra int
+}
+
+var (
+	codecTables   sync.Map // reflect.Type -> *codecTable
+	parameterType = reflect.TypeOf((*Parameter)(nil)).Elem()
+)
+
+// tableOf returns the field table of the struct type t.
+func tableOf(t reflect.Type) *codecTable {
+	if tab, ok := codecTables.Load(t); ok {
+		return tab.(*codecTable)
+	}
+	tab := &codecTable{byKey: map[string]int{}, extra: -1}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup(tagName)
+		if !ok || tag == "-" {
+			continue
+		}
+		key, opt, _ := strings.Cut(tag, ",")
+		if opt == "extra" {
+			if f.Type != reflect.TypeOf(map[string]string(nil)) {
+				panic(fmt.Sprintf("daikin: extra field %s must be map[string]string", f.Name))
+			}
+			tab.extra = i
+			continue
+		}
+		var kind fieldKind
+		switch {
+		case reflect.PointerTo(f.Type).Implements(parameterType):
+			kind = kindParameter
+		case f.Type.Kind() == reflect.Bool:
+			kind = kindBool
+		case f.Type.Kind() == reflect.Int:
+			kind = kindInt
+		default:
+			panic(fmt.Sprintf("daikin: unsupported type %s of field %s", f.Type, f.Name))
+		}
+		tab.byKey[key] = len(tab.fields)
+		tab.fields = append(tab.fields, codecField{key: key, index: i, kind: kind, omitEmpty: opt == "omitempty"})
+	}
+	codecTables.Store(t, tab)
+	return tab
+}
+
+// decodeValues sets the fields of the struct pointed to by v from the
+// key/value pairs of a reply.
+func decodeValues(v interface{}, values map[string]string) error {
+	rv := reflect.ValueOf(v).Elem()
+	tab := tableOf(rv.Type())
+	for k, val := range values {
+		if k == "ret" {
+			if val != returnOk {
+				return fmt.Errorf("device returned error ret=%s", val)
+			}
+			continue
+		}
+		i, ok := tab.byKey[k]
+		if !ok {
+			if tab.extra >= 0 {
+				m := rv.Field(tab.extra)
+				if m.IsNil() {
+					m.Set(reflect.ValueOf(map[string]string{}))
+				}
+				m.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(val))
+			}
+			continue
+		}
+		f := tab.fields[i]
+		fv := rv.Field(f.index)
+		switch f.kind {
+		case kindParameter:
+			if err := fv.Addr().Interface().(Parameter).decode(k, val); err != nil {
+				return err
+			}
+		case kindBool:
+			fv.SetBool(val == "1")
+		case kindInt:
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("error parsing %s=%s: %v", k, val, err)
+			}
+			fv.SetInt(int64(n))
+		}
+	}
+	return nil
+}
+
+// encodeValues returns the query string with the Parameter fields of
+// the struct pointed to by v, in field order.
+func encodeValues(v interface{}) string {
+	rv := reflect.ValueOf(v).Elem()
+	var values []string
+	for _, f := range tableOf(rv.Type()).fields {
+		if f.kind != kindParameter {
+			continue
+		}
+		v := rv.Field(f.index).Addr().Interface().(Parameter).encode()
+		if f.omitEmpty && len(v) == 0 {
+			continue
+		}
+		values = append(values, f.key+"="+v)
+	}
+	return strings.Join(values, "&")
+}
+
+// Parameters returns the Parameter fields of the info struct pointed to
+// by v, e.g. a *ControlInfo, keyed by their wire key.
+func Parameters(v interface{}) map[string]Parameter {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	rv = rv.Elem()
+	params := map[string]Parameter{}
+	for _, f := range tableOf(rv.Type()).fields {
+		if f.kind == kindParameter {
+			params[f.key] = rv.Field(f.index).Addr().Interface().(Parameter)
+		}
+	}
+	return params
+}
@@ -0,0 +1,99 @@
+package daikin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// codecInfo has a field of every kind the codec supports.
+type codecInfo struct {
+	Power       Power             `daikin:"pow"`
+	Temperature Temperature       `daikin:"stemp"`
+	Swing       Swing             `daikin:"f_dir_ud,omitempty"`
+	Enabled     bool              `daikin:"en_x"`
+	Steps       int               `daikin:"steps"`
+	Ignored     string            `daikin:"-"`
+	Extra       map[string]string `daikin:",extra"`
+}
+
+func TestDecodeValues(t *testing.T) {
+	tests := []struct {
+		values map[string]string
+		want   codecInfo
+		err    string
+	}{
+		{values: map[string]string{"ret": "OK", "pow": "1", "stemp": "22.5", "en_x": "1", "steps": "3"},
+			want: codecInfo{Power: PowerOn, Temperature: Temperature{value: 22.5, param: "stemp", set: true},
+				Enabled: true, Steps: 3}},
+		{values: map[string]string{"pow": "0", "en_x": "0", "f_dir_ud": "S", "adv": "", "lpw_flag": "0"},
+			want: codecInfo{Swing: SwingOn, Extra: map[string]string{"adv": "", "lpw_flag": "0"}}},
+		// the tag "-" is not a wire key
+		{values: map[string]string{"-": "x"}, want: codecInfo{Extra: map[string]string{"-": "x"}}},
+		{values: map[string]string{"ret": "PARAM NG", "pow": "1"}, err: "device returned error ret=PARAM NG"},
+		{values: map[string]string{"steps": "many"}, err: "error parsing steps=many"},
+		{values: map[string]string{"pow": "2"}, err: "unknown pow value: 2"},
+	}
+	for _, tt := range tests {
+		var got codecInfo
+		err := decodeValues(&got, tt.values)
+		if len(tt.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%v: got %v, want %q", tt.values, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", tt.values, err)
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: got %+v, want %+v", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeValues(t *testing.T) {
+	tests := []struct {
+		info codecInfo
+		want string
+	}{
+		// only Parameter fields are written, unset ones without
+		// omitempty as not available
+		{codecInfo{Power: PowerOn, Enabled: true, Steps: 3, Extra: map[string]string{"adv": ""}}, "pow=1&stemp=--"},
+		{codecInfo{Temperature: Temperature{value: 22, param: "stemp", set: true}, Swing: SwingStopped}, "pow=0&stemp=22.0&f_dir_ud=0"},
+		{codecInfo{Temperature: Temperature{raw: TemperatureModeDefault}, Swing: SwingOn}, "pow=0&stemp=M&f_dir_ud=S"},
+	}
+	for _, tt := range tests {
+		if got := encodeValues(&tt.info); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.info, got, tt.want)
+		}
+	}
+
+	// the decoded values are written back
+	var info codecInfo
+	if err := decodeValues(&info, map[string]string{"pow": "1", "stemp": "M", "f_dir_ud": "S"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := encodeValues(&info); got != "pow=1&stemp=M&f_dir_ud=S" {
+		t.Errorf("round trip: got %q", got)
+	}
+}
+
+func TestParameters(t *testing.T) {
+	info := &codecInfo{Power: PowerOn}
+	params := Parameters(info)
+	if len(params) != 3 || params["pow"].String() != "On" {
+		t.Errorf("got %v", params)
+	}
+	// the parameters point into the struct
+	params["pow"].decode("pow", "0")
+	if info.Power != PowerOff {
+		t.Error("parameter is a copy")
+	}
+
+	var nilInfo *codecInfo
+	for _, v := range []interface{}{nil, nilInfo, codecInfo{}, new(int), "pow"} {
+		if got := Parameters(v); got != nil {
+			t.Errorf("%#v: got %v", v, got)
+		}
+	}
+}
@@ -1,20 +1,30 @@
 // Package daikin provides functionality to interact with Daikin split
 // system air conditioners equipped with a Wifi module. It is tested to work
-// with the BRP072A42 Wifi interface.
+// with the BRP072A42 Wifi interface. Ducted systems with a SKYFi or
+// BRP15B61 AirBase interface are supported, too, as well as BRP084
+// adapters and firmware 2.8 and newer with the JSON based dsiot protocol.
 package daikin
 
 import (
-	"encoding/csv"
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
 	uriGetBasicInfo    = "/common/basic_info"
 	uriGetRemoteMethod = "/common/get_remote_method"
+	uriRegTerminal     = "/common/register_terminal"
+	uriGetDateTime     = "/common/get_datetime"
+	uriSetDateTime     = "/common/notify_date_time"
+	uriGetWifiSetting  = "/common/get_wifi_setting"
+	uriSetWifiSetting  = "/common/set_wifi_setting"
+	uriReboot          = "/common/reboot"
 	uriGetModelInfo    = "/aircon/get_model_info"
 	uriGetControlInfo  = "/aircon/get_control_info"
 	uriGetSensorInfo   = "/aircon/get_sensor_info"
@@ -28,20 +38,24 @@ const (
 	uriGetScdlTimer    = "/aircon/get_scdltimer"
 	uriGetNotify       = "/aircon/get_notify"
 	uriSetControlInfo  = "/aircon/set_control_info"
+	uriGetZoneSetting  = "/aircon/get_zone_setting"
+	uriSetZoneSetting  = "/aircon/set_zone_setting"
 )
 
-/*
+// Parameter is a value of the unit, which is transferred as key=value
+// pair. The info structs map the keys to their fields with the daikin
+// struct tag.
 type Parameter interface {
-	// String with name and value for URL
-	setUrlValues() string
-	// Set sets this parameter's value.
-	Set(string) error
 	// String returns the human readable value.
 	String() string
-        // Float64 returns the value as float64 for prometheus.
-        Float64() float64
+	// Float64 returns the value as float64 for prometheus, NaN if the
+	// value is not numeric or not available.
+	Float64() float64
+	// decode sets the value from the value v of key param.
+	decode(param string, v string) error
+	// encode returns the value for set requests.
+	encode() string
 }
-*/
 
 const (
 	returnOk  = "OK"
@@ -50,8 +64,26 @@ const (
 
 // Daikin represents the settings of the Daikin unit.
 type Daikin struct {
-	// Address is the IP address of the unit.
+	// Address is the IP address of the unit. It may be prefixed with
+	// http:// or https:// to override the scheme.
 	Address string
+	// Protocol is the protocol family spoken by the Wifi adapter.
+	Protocol Protocol
+	// Credentials are the access credentials of the unit. They are
+	// never marshaled.
+	Credentials Credentials `json:"-" yaml:"-"`
+	// Client is the HTTP client used for requests to the unit. If nil,
+	// a default client accepting self-signed certificates is used.
+	Client *http.Client `json:"-" yaml:"-"`
+	// Policy controls serialization and retries of requests to the
+	// unit. If nil, DefaultRequestPolicy is used.
+	Policy *RequestPolicy
+	// Cache enables reusing recently fetched state. If nil, every
+	// call fetches the state from the unit.
+	Cache *CacheTTL
+	// ParseOptions controls parsing of the replies of the unit. If
+	// nil, DefaultParseOptions is used.
+	ParseOptions *ParseOptions
 	// BasicInfo contains the environment basic info.
 	BasicInfo *BasicInfo
 	// ControlInfo contains the environment control info.
@@ -60,42 +92,44 @@ type Daikin struct {
 	SensorInfo *SensorInfo
 	// Power consumption heating and cooling
 	PowerInfo *PowerInfo
+	// DateTime contains the clock settings of the Wifi adapter.
+	DateTime *DateTime
+	// WifiSetting contains the Wifi client configuration of the adapter.
+	WifiSetting *WifiSetting
+	// Zones contains the zone settings of ducted AirBase systems.
+	Zones *Zones
+	// ModelInfo contains the model information and features.
+	ModelInfo *ModelInfo
+
+	// detected is set once the protocol of the unit is known
+	detected bool
+	// reported is the control info last read from or written to the
+	// unit, changes are validated against it
+	reported *ControlInfo
+
+	// mu guards the info pointers against concurrent Refresh calls
+	mu    sync.RWMutex
+	cache cache
+	// refresh serializes Refresh calls
+	refresh sync.Mutex
 }
 
 // BasicInfo represents basic informations about the device
 type BasicInfo struct {
 	// Name is the human-readable name of the unit.
-	Name Name
+	Name Name `daikin:"name"`
 	// Version is the firmware version
-	Version Version
+	Version Version `daikin:"ver"`
 	// Revision
-	Revision String
+	Revision String `daikin:"rev"`
 	// Type: aircon
-	Type String
+	Type String `daikin:"type"`
+	// Extra contains the values without a field, e.g. mac.
+	Extra map[string]string `daikin:",extra" json:",omitempty" yaml:",omitempty"`
 }
 
 func (b *BasicInfo) populate(values map[string]string) error {
-	for k, v := range values {
-		var err error
-		switch k {
-		case "name":
-			err = b.Name.decode("name", v)
-		case "ver":
-			err = b.Version.decode("ver", v)
-		case "rev":
-			err = b.Revision.decode("rev", v)
-		case "type":
-			err = b.Type.decode("type", v)
-		case "ret":
-			if v != returnOk {
-				err = fmt.Errorf("device returned error ret=%s", v)
-			}
-		}
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return decodeValues(b, values)
 }
 
 func (b *BasicInfo) String() string {
@@ -107,102 +141,94 @@ func (b *BasicInfo) String() string {
 // SensorInfo represents current sensor values.
 type SensorInfo struct {
 	// HomeTemperature is the home (interior) temperature.
-	HomeTemperature Temperature
+	HomeTemperature Temperature `daikin:"htemp"`
 	// OutsideTemperature is the external temperature.
-	OutsideTemperature Temperature
+	OutsideTemperature Temperature `daikin:"otemp"`
 	// Humidity is the current interior humidity.
-	Humidity Humidity
+	Humidity Humidity `daikin:"hhum"`
+	// Extra contains the values without a field.
+	Extra map[string]string `daikin:",extra" json:",omitempty" yaml:",omitempty"`
 }
 
 func (s *SensorInfo) populate(values map[string]string) error {
-	for k, v := range values {
-		var err error
-		switch k {
-		case "htemp":
-			err = s.HomeTemperature.decode("htemp", v)
-		case "otemp":
-			err = s.OutsideTemperature.decode("otemp", v)
-		case "hhum":
-			err = s.Humidity.decode("hhum", v)
-		case "ret":
-			if v != returnOk {
-				err = fmt.Errorf("device returned error ret=%s", v)
-			}
-		}
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return decodeValues(s, values)
 }
 
 func (s *SensorInfo) String() string {
 	return fmt.Sprintf("Inside temperature: %s\nInside humidity: %s\nOutside temperature: %s", s.HomeTemperature.String(), s.Humidity.String(), s.OutsideTemperature.String())
 }
 
+// Format is like String, but prints the temperatures in unit.
+func (s *SensorInfo) Format(unit TemperatureUnit) string {
+	return fmt.Sprintf("Inside temperature: %s\nInside humidity: %s\nOutside temperature: %s", s.HomeTemperature.Format(unit), s.Humidity.String(), s.OutsideTemperature.Format(unit))
+}
+
 // ControlInfo represents the control status of the unit.
 type ControlInfo struct {
 	// Power is the current power status of the unit.
-	Power Power
+	Power Power `daikin:"pow"`
 	// Mode is the operating mode of the unit.
-	Mode Mode
+	Mode Mode `daikin:"mode"`
 	// Fan is the fan speed of the unit.
-	Fan Fan
+	Fan Fan `daikin:"f_rate"`
 	// FanDir is the fan louvre setting of the unit.
-	FanDir FanDir
+	FanDir FanDir `daikin:"f_dir"`
+	// FanDirUD and FanDirLR are the vertical and horizontal louvre
+	// settings of units with 3D airflow. They are unset on other
+	// units and kept consistent with FanDir.
+	FanDirUD Swing `daikin:"f_dir_ud,omitempty" json:",omitempty" yaml:",omitempty"`
+	FanDirLR Swing `daikin:"f_dir_lr,omitempty" json:",omitempty" yaml:",omitempty"`
 	// Temperature is the current set temperature of the unit.
-	Temperature Temperature
+	Temperature Temperature `daikin:"stemp"`
 	// Humidity is the set humidity of the unit.
-	Humidity Humidity
+	Humidity Humidity `daikin:"shum"`
+	// Extra contains the values without a field, e.g. the
+	// temperatures memorized per mode (dt1...). They are not written.
+	Extra map[string]string `daikin:",extra" json:",omitempty" yaml:",omitempty"`
 }
 
 func (c *ControlInfo) urlValues() string {
-	values := c.Power.setUrlValues();
-	values = values + "&" + c.Mode.setUrlValues()
-	values = values + "&" + c.Fan.setUrlValues()
-	values = values + "&" + c.FanDir.setUrlValues()
-	values = values + "&" + c.Temperature.setUrlValues()
-	values = values + "&" + c.Humidity.setUrlValues()
-	return values
+	return encodeValues(c)
 }
 
 func (c *ControlInfo) populate(values map[string]string) error {
-	for k, v := range values {
-		var err error
-		switch k {
-		case "pow":
-			err = c.Power.decode(v)
-		case "mode":
-			err = c.Mode.Decode(v)
-		case "stemp":
-			err = c.Temperature.decode("stemp", v)
-		case "shum":
-			err = c.Humidity.decode("shum", v)
-		case "f_rate":
-			err = c.Fan.Decode(v)
-		case "f_dir":
-			err = c.FanDir.decode(v)
-		case "ret":
-			if v != returnOk {
-				err = fmt.Errorf("device returned error ret=%s", v)
-			}
-		}
-		if err != nil {
-			return err
-		}
+	if err := decodeValues(c, values); err != nil {
+		return err
+	}
+	if c.FanDirUD.IsSet() && c.FanDirLR.IsSet() {
+		// the separate louvres are authoritative on 3D airflow units
+		c.FanDir = FanDirOf(c.FanDirUD, c.FanDirLR)
 	}
 	return nil
 }
 
 func (c *ControlInfo) String() string {
-	return fmt.Sprintf("Power: %s\nMode: %s\nSet temperature: %s\nSet humidity: %s\nFan speed: %s\nFan louvre: %s",
-		c.Power.String(), c.Mode.String(), c.Temperature.String(), c.Humidity.String(), c.Fan.String(), c.FanDir.String())
+	return fmt.Sprintf("Power: %s\nMode: %s\nSet temperature: %s\nSet humidity: %s\nFan speed: %s\nFan louvre: %s%s",
+		c.Power.String(), c.Mode.String(), c.Temperature.String(), c.Humidity.String(), c.Fan.String(), c.FanDir.String(), c.louvres())
+}
+
+// Format is like String, but prints the set temperature in unit.
+func (c *ControlInfo) Format(unit TemperatureUnit) string {
+	return fmt.Sprintf("Power: %s\nMode: %s\nSet temperature: %s\nSet humidity: %s\nFan speed: %s\nFan louvre: %s%s",
+		c.Power.String(), c.Mode.String(), c.Temperature.Format(unit), c.Humidity.String(), c.Fan.String(), c.FanDir.String(), c.louvres())
+}
+
+// louvres returns the lines of the separate louvres of 3D airflow
+// units, an empty string for other units.
+func (c *ControlInfo) louvres() string {
+	if !c.FanDirUD.IsSet() && !c.FanDirLR.IsSet() {
+		return ""
+	}
+	return fmt.Sprintf("\nVertical louvre: %s\nHorizontal louvre: %s", c.FanDirUD.String(), c.FanDirLR.String())
 }
 
 // PowerInfo represents power usage over the current day
 type PowerInfo struct {
 	DayHeat KWattHours
 	DayCool KWattHours
+	// Week is the power usage of the last seven days, the last
+	// element is the current day.
+	Week []KWattHours
 }
 
 // ret=OK,curr_day_heat=0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0,prev_1day_heat=0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0,curr_day_cool=0/1/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0,prev_1day_cool=0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0
@@ -210,8 +236,7 @@ func (w *PowerInfo) populate(values map[string]string) error {
 	for k, v := range values {
 		var err error
 		switch k {
-		case "curr_day_heat":
-		case "curr_day_cool":
+		case "curr_day_heat", "curr_day_cool":
 			elems := strings.Split(v, "/")
 			if len(elems) != 24 {
 				return fmt.Errorf("expected 24 elements in day power data, got %d", len(elems))
@@ -220,7 +245,7 @@ func (w *PowerInfo) populate(values map[string]string) error {
 			for i := 0; i < 24; i++ {
 				n, err := strconv.Atoi(elems[i]);
 				if err != nil {
-					return fmt.Errorf("error parsing day power data[i]=%s: %v", i, elems[i], err)
+					return fmt.Errorf("error parsing day power data[%d]=%s: %v", i, elems[i], err)
 				}
 				total = total + int64(n);
 			}
@@ -231,6 +256,19 @@ func (w *PowerInfo) populate(values map[string]string) error {
 			} else {
 				w.DayCool.decode(k, strconv.FormatFloat(kWh, 'f', 1, 64))
 			}
+		// ret=OK,today_runtime=601,datas=0/0/0/0/0/0/1000
+		case "datas":
+			elems := strings.Split(v, "/")
+			w.Week = make([]KWattHours, len(elems))
+			for i := range elems {
+				n, err := strconv.Atoi(elems[i])
+				if err != nil {
+					return fmt.Errorf("error parsing week power data[%d]=%s: %v", i, elems[i], err)
+				}
+				// data is Wh
+				kWh := float64(n) / 1000.0
+				w.Week[i].decode(k, strconv.FormatFloat(kWh, 'f', 3, 64))
+			}
 		case "ret":
 			if v != returnOk {
 				err = fmt.Errorf("device returned error ret=%s", v)
@@ -244,110 +282,349 @@ func (w *PowerInfo) populate(values map[string]string) error {
 }
 
 func (c *PowerInfo) String() string {
-	return fmt.Sprintf("Power consumption cooling: %s kWh\nPower consumption heating: %s kWh",
+	ret := fmt.Sprintf("Power consumption cooling: %s kWh\nPower consumption heating: %s kWh",
 		c.DayCool.String(), c.DayHeat.String())
+	if len(c.Week) > 0 {
+		var week []string
+		for i := range c.Week {
+			week = append(week, c.Week[i].String())
+		}
+		ret = ret + fmt.Sprintf("\nPower consumption last days: %s kWh", strings.Join(week, "/"))
+	}
+	return ret
 }
 
+// ErrNotSupported is returned if the unit does not support a request.
+var ErrNotSupported = errors.New("not supported by device")
 
-func (d *Daikin) parseResponse(resp *http.Response) (map[string]string, error) {
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+func (d *Daikin) parseResponse(resp *http.Response, body []byte) (map[string]string, error) {
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", resp.Request.URL.Path, ErrNotSupported)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+	values, err := d.parseOptions().Parse(body)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%s: %w", resp.Request.URL.Path, err)
+	}
+	return values, nil
+}
+
+func (d *Daikin) parseOptions() *ParseOptions {
+	if d.ParseOptions != nil {
+		return d.ParseOptions
+	}
+	return &DefaultParseOptions
+}
+
+func statusError(resp *http.Response) error {
+	return fmt.Errorf("%s: device returned HTTP status %s",
+		resp.Request.URL.Path, resp.Status)
+}
+
+// get queries uri with the optional query string on the unit and
+// returns the parsed key/value pairs.
+func (d *Daikin) get(ctx context.Context, uri string, query string) (map[string]string, error) {
+	return d.request(ctx, true, uri, query)
+}
+
+// set sends the query string to uri on the unit and checks the result.
+func (d *Daikin) set(ctx context.Context, uri string, query string) error {
+	vals, err := d.request(ctx, false, uri, query)
+	if err != nil {
+		return err
 	}
-	r := csv.NewReader(strings.NewReader(string(body)))
-	records, err := r.ReadAll()
+	return checkRet(uri, vals)
+}
+
+// request sends a GET request to uri on the unit. Only idempotent
+// requests are retried.
+func (d *Daikin) request(ctx context.Context, idempotent bool, uri string, query string) (map[string]string, error) {
+	resp, body, err := d.exchange(ctx, idempotent, func() (*http.Request, error) {
+		return d.newRequest(ctx, http.MethodGet, uri, query, nil)
+	})
 	if err != nil {
 		return nil, err
 	}
-	if len(records) != 1 {
-		return nil, fmt.Errorf("Have %d rows of records, want just one", len(records))
-	}
+	return d.parseResponse(resp, body)
+}
 
-	values := map[string]string{}
-	for _, rec := range records[0] {
-		parts := strings.SplitN(rec, "=", 2)
-		values[parts[0]] = parts[1]
+// fetch queries the endpoint uri in the protocol of the unit and
+// returns the values in BRP representation.
+func (d *Daikin) fetch(ctx context.Context, uri string) (map[string]string, error) {
+	switch d.Protocol {
+	case ProtocolAirBase:
+		vals, err := d.get(ctx, airbasePrefix+uri, "")
+		if err != nil {
+			return nil, err
+		}
+		return airbaseFromWire(vals), nil
+	case ProtocolDsiot:
+		return d.dsiotFetch(ctx, uri)
 	}
-	return values, nil
+	return d.get(ctx, uri, "")
+}
 
+// store sends the BRP query string to the endpoint uri in the
+// protocol of the unit.
+func (d *Daikin) store(ctx context.Context, uri string, query string) error {
+	switch d.Protocol {
+	case ProtocolAirBase:
+		if uri == uriSetControlInfo {
+			query = airbaseToWire(query, d.Credentials.Password)
+		}
+		return d.set(ctx, airbasePrefix+uri, query)
+	case ProtocolDsiot:
+		return d.dsiotStore(ctx, uri, query)
+	}
+	return d.set(ctx, uri, query)
 }
 
-// GetBasicInfo gets the basic information for the unit.
+// GetBasicInfo gets the basic information for the unit. If the unit
+// does not know the BRP endpoint, the AirBase and dsiot protocols are
+// tried. If it knows the BRP endpoint, but not /aircon, the dsiot
+// protocol is tried.
 func (d *Daikin) GetBasicInfo() error {
-	resp, err := http.Get(fmt.Sprintf("http://%s%s", d.Address, uriGetBasicInfo))
+	return d.getBasicInfo(context.Background())
+}
+
+func (d *Daikin) getBasicInfo(ctx context.Context) error {
+	if d.cached(SectionBasicInfo) {
+		return nil
+	}
+	vals, err := d.fetch(ctx, uriGetBasicInfo)
+	if errors.Is(err, ErrNotSupported) && d.Protocol == ProtocolBRP {
+		for _, p := range []Protocol{ProtocolAirBase, ProtocolDsiot} {
+			d.Protocol = p
+			vals, err = d.fetch(ctx, uriGetBasicInfo)
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			d.Protocol = ProtocolBRP
+		}
+	}
 	if err != nil {
 		return err
 	}
-	d.BasicInfo = &BasicInfo{}
-	vals, err := d.parseResponse(resp)
-	if err != nil {
+	if d.Protocol != ProtocolBRP {
+		d.detected = true
+	} else if !d.detected {
+		d.probeDsiot(ctx)
+	}
+	b := &BasicInfo{}
+	if err := b.populate(vals); err != nil {
 		return err
 	}
-	return d.BasicInfo.populate(vals)
+	d.mu.Lock()
+	d.BasicInfo = b
+	d.mu.Unlock()
+	d.fetched(SectionBasicInfo)
+	return nil
+}
+
+// probeDsiot switches to the dsiot protocol, if the unit answers
+// /common/basic_info, but not /aircon/get_control_info and knows the
+// dsiot protocol. This is the case with firmware 2.8 of the BRP069
+// adapters. If the probe fails, it is repeated with the next basic
+// info request.
+func (d *Daikin) probeDsiot(ctx context.Context) {
+	_, err := d.get(ctx, uriGetControlInfo, "")
+	if err == nil {
+		d.detected = true
+		return
+	}
+	if !errors.Is(err, ErrNotSupported) {
+		return
+	}
+	d.Protocol = ProtocolDsiot
+	if _, err := d.dsiotFetch(ctx, uriGetBasicInfo); err != nil {
+		d.Protocol = ProtocolBRP
+		if !errors.Is(err, ErrNotSupported) {
+			return
+		}
+	}
+	d.detected = true
 }
 
 // Set configures the current setting to the unit.
 func (d *Daikin) SetControlInfo() error {
-	url := fmt.Sprintf("http://%s%s?%s", d.Address,
-                           uriSetControlInfo, d.ControlInfo.urlValues())
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
+	if d.ControlInfo == nil {
+		return fmt.Errorf("no control settings, call GetControlInfo first")
 	}
-	vals, err := d.parseResponse(resp)
-	if err != nil {
+	if err := d.ControlInfo.ValidateChange(d.reportedControl(), d.ModelInfo); err != nil {
 		return err
 	}
-	if v := vals["ret"]; v != "OK" {
-		return fmt.Errorf("device returned error ret=%s", v)
+	d.Invalidate(SectionControlInfo)
+	if err := d.store(context.Background(), uriSetControlInfo, d.ControlInfo.urlValues()); err != nil {
+		return err
 	}
+	d.setReported(d.ControlInfo)
 	return nil
 }
 
+// reportedControl returns the control info last read from or written to
+// the unit, nil if there is none.
+func (d *Daikin) reportedControl() *ControlInfo {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.reported
+}
+
+// setReported records a copy of c as the settings of the unit.
+func (d *Daikin) setReported(c *ControlInfo) {
+	ci := *c
+	d.mu.Lock()
+	d.reported = &ci
+	d.mu.Unlock()
+}
+
 // GetControlInfo gets the current control settings for the unit.
 func (d *Daikin) GetControlInfo() error {
-	resp, err := http.Get(fmt.Sprintf("http://%s%s", d.Address, uriGetControlInfo))
+	return d.getControlInfo(context.Background())
+}
+
+func (d *Daikin) getControlInfo(ctx context.Context) error {
+	if d.cached(SectionControlInfo) {
+		return nil
+	}
+	vals, err := d.fetch(ctx, uriGetControlInfo)
 	if err != nil {
 		return err
 	}
-	d.ControlInfo = &ControlInfo{}
-	vals, err := d.parseResponse(resp)
-	if err != nil {
+	c := &ControlInfo{}
+	if err := c.populate(vals); err != nil {
 		return err
 	}
-	return d.ControlInfo.populate(vals)
+	d.setReported(c)
+	d.mu.Lock()
+	d.ControlInfo = c
+	d.mu.Unlock()
+	d.fetched(SectionControlInfo)
+	return nil
 }
 
 // GetSensorInfo gets the current sensor values for the unit.
 func (d *Daikin) GetSensorInfo() error {
-	resp, err := http.Get(fmt.Sprintf("http://%s%s", d.Address, uriGetSensorInfo))
+	return d.getSensorInfo(context.Background())
+}
+
+func (d *Daikin) getSensorInfo(ctx context.Context) error {
+	if d.cached(SectionSensorInfo) {
+		return nil
+	}
+	vals, err := d.fetch(ctx, uriGetSensorInfo)
 	if err != nil {
 		return err
 	}
-	d.SensorInfo = &SensorInfo{}
-	vals, err := d.parseResponse(resp)
-	if err != nil {
+	s := &SensorInfo{}
+	if err := s.populate(vals); err != nil {
 		return err
 	}
-	return d.SensorInfo.populate(vals)
+	d.mu.Lock()
+	d.SensorInfo = s
+	d.mu.Unlock()
+	d.fetched(SectionSensorInfo)
+	return nil
 }
 
 // GetPowerInfo gets the current power consumption for the unit.
+// AirBase units don't report the power consumption, in this case
+// ErrNotSupported is returned and PowerInfo is nil.
 func (d *Daikin) GetPowerInfo() error {
-	resp, err := http.Get(fmt.Sprintf("http://%s%s", d.Address, uriGetDayPowerEx))
+	return d.getPowerInfo(context.Background())
+}
+
+func (d *Daikin) getPowerInfo(ctx context.Context) error {
+	if d.Protocol == ProtocolAirBase {
+		d.mu.Lock()
+		d.PowerInfo = nil
+		d.mu.Unlock()
+		return fmt.Errorf("%s: %w", uriGetDayPowerEx, ErrNotSupported)
+	}
+	if d.cached(SectionPowerInfo) {
+		return nil
+	}
+	p := &PowerInfo{}
+	supported := false
+	for _, uri := range []string{uriGetDayPowerEx, uriGetWeekPower} {
+		vals, err := d.fetch(ctx, uri)
+		if errors.Is(err, ErrNotSupported) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.populate(vals); err != nil {
+			return err
+		}
+		supported = true
+	}
+	if !supported {
+		p = nil
+	}
+	d.mu.Lock()
+	d.PowerInfo = p
+	d.mu.Unlock()
+	if !supported {
+		return fmt.Errorf("%s: %w", uriGetDayPowerEx, ErrNotSupported)
+	}
+	d.fetched(SectionPowerInfo)
+	return nil
+}
+
+// GetModelInfo gets the model information and features of the unit.
+func (d *Daikin) GetModelInfo() error {
+	return d.getModelInfo(context.Background())
+}
+
+func (d *Daikin) getModelInfo(ctx context.Context) error {
+	vals, err := d.fetch(ctx, uriGetModelInfo)
 	if err != nil {
 		return err
 	}
-	d.PowerInfo = &PowerInfo{}
-	vals, err := d.parseResponse(resp)
+	m := &ModelInfo{}
+	if err := m.populate(vals); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	if d.ModelInfo != nil {
+		// keep the limits set by the caller
+		for mode, r := range d.ModelInfo.Setpoints {
+			if m.Setpoints == nil {
+				m.Setpoints = map[Mode]SetpointRange{}
+			}
+			m.Setpoints[mode] = r
+		}
+	}
+	d.ModelInfo = m
+	d.mu.Unlock()
+	d.fetched(SectionModelInfo)
+	return nil
+}
+
+// GetDateTime gets the clock settings of the Wifi adapter.
+func (d *Daikin) GetDateTime() error {
+	d.DateTime = &DateTime{}
+	vals, err := d.fetch(context.Background(), uriGetDateTime)
 	if err != nil {
 		return err
 	}
-	return d.PowerInfo.populate(vals)
+	return d.DateTime.populate(vals)
+}
+
+// SetDateTime sets the clock of the Wifi adapter to t. If zone is not
+// empty, the time zone of the adapter is set, too.
+func (d *Daikin) SetDateTime(t time.Time, zone string) error {
+	return d.SetClock(context.Background(), t, zone)
 }
 
 func (d *Daikin) String() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
 	var ret string
 	if d.BasicInfo != nil {
 		ret = ret + d.BasicInfo.String() + "\n"
@@ -361,5 +638,11 @@ func (d *Daikin) String() string {
 	if d.PowerInfo != nil {
 		ret = ret + d.PowerInfo.String() + "\n"
 	}
+	if d.Zones != nil {
+		ret = ret + d.Zones.String() + "\n"
+	}
+	if d.DateTime != nil {
+		ret = ret + d.DateTime.String() + "\n"
+	}
 	return ret
 }
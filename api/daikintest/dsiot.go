@@ -0,0 +1,152 @@
+package daikintest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// node is a resource of the dsiot tree.
+type node struct {
+	Pn  string          `json:"pn"`
+	Pt  int             `json:"pt,omitempty"`
+	Pv  json.RawMessage `json:"pv,omitempty"`
+	Pch []*node         `json:"pch,omitempty"`
+}
+
+func (n *node) merge(o *node) {
+	if len(o.Pv) > 0 {
+		n.Pv = o.Pv
+	}
+	for _, oc := range o.Pch {
+		found := false
+		for _, c := range n.Pch {
+			if c.Pn == oc.Pn {
+				c.merge(oc)
+				found = true
+				break
+			}
+		}
+		if !found {
+			n.Pch = append(n.Pch, oc)
+		}
+	}
+}
+
+// DsiotServer is a fake BRP084 adapter speaking the JSON protocol on
+// /dsiot/multireq. Endpoints added with Set are served as key=value,
+// like /common/basic_info of BRP069 adapters with firmware 2.8.
+type DsiotServer struct {
+	*Server
+	resources map[string]*node
+}
+
+// NewDsiotServer starts a fake BRP084 adapter with firmware 2.8. The
+// caller should call Close when finished.
+func NewDsiotServer() *DsiotServer {
+	s := &DsiotServer{
+		Server:    &Server{endpoints: map[string]map[string]string{}},
+		resources: map[string]*node{},
+	}
+	for to, tree := range dsiotResources {
+		var n node
+		if err := json.Unmarshal([]byte(tree), &n); err != nil {
+			panic(err)
+		}
+		s.resources[to] = &n
+	}
+	s.Server.Server = httptest.NewServer(s)
+	return s
+}
+
+var dsiotResources = map[string]string{
+	"/dsiot/edge.adp_i": `{"pn":"adp_i","pch":[
+		{"pn":"name","pv":"Living Room"},
+		{"pn":"ver","pv":"2_8_0"},
+		{"pn":"rev","pv":"0"},
+		{"pn":"mac","pv":"001122334477"}]}`,
+	"/dsiot/edge/adr_0100.dgc_status": `{"pn":"dgc_status","pch":[
+		{"pn":"e_1002","pch":[
+			{"pn":"e_A002","pch":[{"pn":"p_01","pv":"00"}]},
+			{"pn":"e_3001","pch":[
+				{"pn":"p_01","pv":"0200"},
+				{"pn":"p_02","pv":"2e"},
+				{"pn":"p_03","pv":"2a"},
+				{"pn":"p_05","pv":"000000"},
+				{"pn":"p_06","pv":"000000"},
+				{"pn":"p_09","pv":"0A00"},
+				{"pn":"p_0A","pv":"0A00"}]},
+			{"pn":"e_A00B","pch":[
+				{"pn":"p_01","pv":"17"},
+				{"pn":"p_02","pv":"32"}]}]}]}`,
+	"/dsiot/edge/adr_0200.dgc_status": `{"pn":"dgc_status","pch":[
+		{"pn":"e_1003","pch":[
+			{"pn":"e_A00D","pch":[{"pn":"p_01","pv":"14"}]}]}]}`,
+	"/dsiot/edge/adr_0100.i_power.week_power": `{"pn":"week_power","pch":[
+		{"pn":"today_runtime","pv":"35"},
+		{"pn":"datas","pv":[0,100,0,1200,800,0,300]}]}`,
+	"/dsiot/edge/adr_0100.i_power.day_power_ex": `{"pn":"day_power_ex","pch":[
+		{"pn":"curr_day_heat","pv":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]},
+		{"pn":"curr_day_cool","pv":[0,0,0,0,0,0,0,0,0,0,0,0,1,2,3,0,0,0,0,0,0,0,0,0]},
+		{"pn":"prev_1day_heat","pv":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]},
+		{"pn":"prev_1day_cool","pv":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,4,0,0,0,0,0,0,0,0,0]}]}`,
+}
+
+// ServeHTTP answers the batched requests on /dsiot/multireq.
+func (s *DsiotServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.URL.RequestURI())
+	if s.failures > 0 {
+		s.failures--
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+		return
+	}
+
+	if values, ok := s.endpoints[r.URL.Path]; ok {
+		writeValues(w, "OK", values)
+		return
+	}
+	if r.Method != http.MethodPost || r.URL.Path != "/dsiot/multireq" {
+		http.NotFound(w, r)
+		return
+	}
+	var req struct {
+		Requests []struct {
+			Op int    `json:"op"`
+			To string `json:"to"`
+			Pc *node  `json:"pc"`
+		} `json:"requests"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	type response struct {
+		Fr  string `json:"fr"`
+		Pc  *node  `json:"pc,omitempty"`
+		Rsc int    `json:"rsc"`
+	}
+	var resp struct {
+		Responses []response `json:"responses"`
+	}
+	for _, rq := range req.Requests {
+		to := strings.SplitN(rq.To, "?", 2)[0]
+		n, ok := s.resources[to]
+		switch {
+		case !ok:
+			resp.Responses = append(resp.Responses, response{Fr: to, Rsc: 4004})
+		case rq.Op == 2:
+			resp.Responses = append(resp.Responses, response{Fr: to, Pc: n, Rsc: 2000})
+		case rq.Op == 3 && rq.Pc != nil:
+			n.merge(rq.Pc)
+			resp.Responses = append(resp.Responses, response{Fr: to, Rsc: 2004})
+		default:
+			resp.Responses = append(resp.Responses, response{Fr: to, Rsc: 4000})
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&resp)
+}
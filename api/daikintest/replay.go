@@ -0,0 +1,35 @@
+package daikintest
+
+import (
+	"net/http/httptest"
+	"strings"
+
+	"github.com/thkukuk/daikin-gomod/api"
+)
+
+// ReplayServer serves a fixture recorded with daikin.Recorder, e.g. by
+// "daikin-ac-ctrl capture", to replay the responses of a real unit.
+type ReplayServer struct {
+	*httptest.Server
+}
+
+// NewReplayServer starts a server replaying f. The caller should call
+// Close when finished.
+func NewReplayServer(f *daikin.Fixture) *ReplayServer {
+	return &ReplayServer{Server: httptest.NewServer(daikin.NewReplay(f))}
+}
+
+// LoadReplayServer starts a server replaying the fixture file.
+func LoadReplayServer(file string) (*ReplayServer, error) {
+	f, err := daikin.LoadFixture(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayServer(f), nil
+}
+
+// Address returns the host:port of the server, suitable for
+// Daikin.Address.
+func (s *ReplayServer) Address() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
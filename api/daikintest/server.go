@@ -0,0 +1,249 @@
+// Package daikintest provides a fake Daikin Wifi adapter, which serves
+// the key=value endpoints over HTTP on the loopback interface. It is
+// meant for tests and for trying out commands without a real unit.
+package daikintest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Server is a fake Daikin Wifi adapter.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	endpoints map[string]map[string]string
+	requests  []string
+	failures  int
+}
+
+// NewServer starts a fake adapter with the default endpoint values of
+// a BRP072A42 adapter. The caller should call Close when finished.
+func NewServer() *Server {
+	s := &Server{
+		endpoints: map[string]map[string]string{},
+	}
+	for path, values := range defaultEndpoints {
+		s.endpoints[path] = map[string]string{}
+		for k, v := range values {
+			s.endpoints[path][k] = v
+		}
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// NewAirBaseServer starts a fake AirBase (BRP15B61) adapter, which
+// serves the endpoints below /skyfi. The caller should call Close when
+// finished.
+func NewAirBaseServer() *Server {
+	s := &Server{
+		endpoints: map[string]map[string]string{},
+	}
+	for path, values := range airbaseEndpoints {
+		s.endpoints[path] = map[string]string{}
+		for k, v := range values {
+			s.endpoints[path][k] = v
+		}
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+var airbaseEndpoints = map[string]map[string]string{
+	"/skyfi/common/basic_info": {
+		"type": "aircon", "reg": "au", "dst": "1", "ver": "1_1_8",
+		"rev": "1F", "pow": "1", "err": "0", "location": "0",
+		"name": "%44%75%63%74", "icon": "0", "method": "polling",
+		"port": "30050", "id": "", "pw": "", "lpw_flag": "0",
+		"adp_kind": "3", "led": "1", "en_setzone": "1",
+		"mac": "001122334466", "adp_mode": "run",
+	},
+	"/skyfi/aircon/get_model_info": {
+		"model": "NOTSUPPORT", "type": "N", "humd": "0", "s_humd": "0",
+		"en_zone": "8", "en_filter_sign": "1", "acled": "1",
+		"land": "0", "elec": "0", "temp": "1", "m_dtct": "0",
+		"ac_dst": "au", "dmnd": "0", "en_temp_setting": "1",
+		"en_frate": "1", "en_fdir": "0", "en_rtemp_a": "0",
+		"en_spmode": "0", "en_ipw_sep": "0", "en_scdltmr": "0",
+		"en_mompow": "0", "en_patrol": "0", "en_airside": "0",
+		"en_quick_timer": "1", "en_auto": "1", "en_dry": "1",
+		"en_common_zone": "0", "cool_l": "16", "cool_h": "32",
+		"heat_l": "16", "heat_h": "32", "frate_steps": "3",
+		"en_frate_auto": "1",
+	},
+	"/skyfi/aircon/get_control_info": {
+		"pow": "1", "mode": "2", "stemp": "23", "shum": "0",
+		"f_rate": "3", "f_auto": "0", "f_airside": "0",
+		"dt1": "23", "dt2": "23", "dt3": "23", "dt4": "23",
+		"dt5": "23", "dt7": "23", "dh1": "0",
+	},
+	"/skyfi/aircon/get_sensor_info": {
+		"err": "0", "htemp": "24", "otemp": "-",
+	},
+	"/skyfi/aircon/get_zone_setting": {
+		"zone_name":  "%20%20%20Zone%201%3b%20%20%20Zone%202%3b%20%20%20Zone%203%3b%20%20%20Zone%204%3b%20%20%20Zone%205%3b%20%20%20Zone%206%3b%20%20%20Zone%207%3b%20%20%20Zone%208",
+		"zone_onoff": "1%3b1%3b0%3b0%3b0%3b0%3b0%3b0",
+	},
+}
+
+var defaultEndpoints = map[string]map[string]string{
+	"/common/basic_info": {
+		"type": "aircon", "reg": "eu", "dst": "1", "ver": "1_14_68",
+		"rev": "C3FF8A6", "pow": "0", "err": "0", "location": "0",
+		"name": "%46%61%6b%65", "icon": "0", "method": "home only",
+		"port": "30050", "id": "", "pw": "", "lpw_flag": "0",
+		"adp_kind": "2", "led": "1", "en_setzone": "1",
+		"mac": "001122334455", "adp_mode": "run",
+	},
+	"/common/get_datetime": {
+		"sta": "2", "cur": "2023/1/15 21:26:49", "reg": "eu",
+		"dst": "1", "zone": "54",
+	},
+	"/common/get_wifi_setting": {
+		"ssid": "", "security": "mixed", "key": "", "link": "0",
+	},
+	"/aircon/get_model_info": {
+		"model": "NOTSUPPORT", "type": "N", "pv": "2", "cpv": "2",
+		"humd": "0", "s_humd": "0", "en_frate": "1", "en_fdir": "1",
+		"s_fdir": "3",
+	},
+	"/aircon/get_control_info": {
+		"pow": "0", "mode": "3", "adv": "", "stemp": "22.0",
+		"shum": "0", "f_rate": "A", "f_dir": "0",
+	},
+	"/aircon/get_sensor_info": {
+		"htemp": "23.5", "hhum": "-", "otemp": "12.0", "err": "0",
+		"cmpfreq": "0",
+	},
+	"/aircon/get_day_power_ex": {
+		"curr_day_heat":  "0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0",
+		"prev_1day_heat": "0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0",
+		"curr_day_cool":  "0/0/0/0/0/0/0/1/2/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0",
+		"prev_1day_cool": "0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0",
+	},
+}
+
+// Address returns the host:port of the fake adapter, suitable for
+// Daikin.Address.
+func (s *Server) Address() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+// Set sets key to the raw (wire encoded) value on the endpoint path.
+func (s *Server) Set(path string, key string, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.endpoints[path]; !ok {
+		s.endpoints[path] = map[string]string{}
+	}
+	s.endpoints[path][key] = value
+}
+
+// Delete removes the endpoint path, it is answered with 404 like on
+// units without the endpoint.
+func (s *Server) Delete(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.endpoints, path)
+}
+
+// Get returns the raw (wire encoded) value of key on the endpoint path.
+func (s *Server) Get(path string, key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.endpoints[path][key]
+}
+
+// Fail makes the next n requests fail with HTTP status 503, like an
+// adapter which is busy or just rebooting.
+func (s *Server) Fail(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = n
+}
+
+// Requests returns the request URIs received so far.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// ServeHTTP answers like a Daikin adapter. Requests to a set_ endpoint
+// store the query parameters verbatim in the matching get_ endpoint.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.URL.RequestURI())
+	if s.failures > 0 {
+		s.failures--
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+		return
+	}
+
+	path := r.URL.Path
+	if values, ok := s.endpoints[path]; ok {
+		writeValues(w, "OK", values)
+		return
+	}
+	if i := strings.LastIndex(path, "/set_"); i >= 0 {
+		getPath := path[:i] + "/get_" + path[i+len("/set_"):]
+		values, ok := s.endpoints[getPath]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		for _, p := range strings.Split(r.URL.RawQuery, "&") {
+			kv := strings.SplitN(p, "=", 2)
+			if len(kv) != 2 {
+				writeValues(w, "PARAM NG", nil)
+				return
+			}
+			values[kv[0]] = kv[1]
+		}
+		writeValues(w, "OK", nil)
+		return
+	}
+	switch path {
+	case "/common/notify_date_time":
+		q := r.URL.Query()
+		if dt, ok := s.endpoints["/common/get_datetime"]; ok {
+			dt["cur"] = q.Get("date") + " " + q.Get("time")
+			dt["sta"] = "2"
+			if z := q.Get("zone"); len(z) > 0 {
+				dt["zone"] = z
+			}
+		}
+		writeValues(w, "OK", nil)
+	case "/common/register_terminal":
+		if r.Header.Get("X-Daikin-uuid") == "" || r.URL.Query().Get("key") == "" {
+			writeValues(w, "PARAM NG", nil)
+			return
+		}
+		writeValues(w, "OK", nil)
+	case "/common/reboot":
+		writeValues(w, "OK", nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeValues(w http.ResponseWriter, ret string, values map[string]string) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	body := "ret=" + ret
+	for _, k := range keys {
+		body = body + "," + k + "=" + values[k]
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(body))
+}
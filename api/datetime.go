@@ -0,0 +1,122 @@
+package daikin
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Layout of the cur value returned by /common/get_datetime.
+const dateTimeLayout = "2006/1/2 15:04:05"
+
+// DateTime represents the clock settings of the Wifi adapter. Timers,
+// schedules and the hourly power buckets depend on it.
+type DateTime struct {
+	// Time is the current wall clock time of the adapter. The adapter
+	// does not report the offset of its zone, Time is in UTC, see In.
+	Time time.Time
+	// Synced is true if the adapter clock has been set.
+	Synced bool
+	// Region is the region code of the adapter (e.g. "eu").
+	Region String
+	// Zone is the time zone index configured on the adapter.
+	Zone String
+	// DST is true if daylight saving time is enabled.
+	DST bool
+}
+
+// ret=OK,sta=2,cur=2023/1/15 21:26:49,reg=eu,dst=1,zone=54
+func (t *DateTime) populate(values map[string]string) error {
+	for k, v := range values {
+		var err error
+		switch k {
+		case "cur":
+			err = t.decodeTime(v)
+		case "sta":
+			t.Synced = v == "2"
+		case "reg":
+			err = t.Region.decode("reg", v)
+		case "zone":
+			err = t.Zone.decode("zone", v)
+		case "dst":
+			t.DST = v == "1"
+		case "ret":
+			if v != returnOk {
+				err = fmt.Errorf("device returned error ret=%s", v)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *DateTime) decodeTime(v string) error {
+	if v == "-" || v == "--" {
+		t.Time = time.Time{}
+		return nil
+	}
+	s, err := url.PathUnescape(v)
+	if err != nil {
+		return err
+	}
+	tm, err := time.ParseInLocation(dateTimeLayout, s, time.UTC)
+	if err != nil {
+		return fmt.Errorf("DateTime: error parsing cur=%s: %v", v, err)
+	}
+	t.Time = tm
+	return nil
+}
+
+// In returns the wall clock time of the adapter in loc, the zone the
+// adapter clock was set in.
+func (t *DateTime) In(loc *time.Location) time.Time {
+	if t.Time.IsZero() {
+		return time.Time{}
+	}
+	tm := t.Time
+	return time.Date(tm.Year(), tm.Month(), tm.Day(), tm.Hour(), tm.Minute(), tm.Second(), 0, loc)
+}
+
+// Offset returns the difference between the adapter clock and now. The
+// adapter clock is taken to be in the zone of now.
+func (t *DateTime) Offset(now time.Time) time.Duration {
+	if t.Time.IsZero() {
+		return 0
+	}
+	return t.In(now.Location()).Sub(now)
+}
+
+func (t *DateTime) String() string {
+	cur := "N/A"
+	if !t.Time.IsZero() {
+		cur = t.Time.Format(dateTimeLayout)
+	}
+	return fmt.Sprintf("Adapter time: %s\nTime zone: %s\nRegion: %s\nDST: %t",
+		cur, t.Zone.String(), t.Region.String(), t.DST)
+}
+
+// CheckClockZone returns an error if zone is neither empty nor a time
+// zone index as reported by /common/get_datetime, e.g. 54.
+func CheckClockZone(zone string) error {
+	if len(zone) == 0 {
+		return nil
+	}
+	if n, err := strconv.Atoi(zone); err != nil || n < 0 {
+		return fmt.Errorf("invalid time zone %q, expected the zone index of the adapter", zone)
+	}
+	return nil
+}
+
+// dateTimeUrlValues returns the query string for /common/notify_date_time.
+// The zone index is sent with the key get_datetime reports it.
+func dateTimeUrlValues(t time.Time, zone string) string {
+	values := "date=" + t.Format("2006/01/02")
+	values = values + "&time=" + t.Format("15:04:05")
+	if len(zone) > 0 {
+		values = values + "&zone=" + zone
+	}
+	return values
+}
@@ -0,0 +1,58 @@
+package daikin_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+func TestSetClock(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+	s.Set("/common/get_datetime", "sta", "0")
+
+	// the adapter clock is set to the wall clock of the zone of now
+	loc := time.FixedZone("UTC+9", 9*60*60)
+	now := time.Now().In(loc).Truncate(time.Second)
+	d := newDaikin(s.Address())
+	if err := d.SetClock(context.Background(), now, "61"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.GetDateTime(); err != nil {
+		t.Fatal(err)
+	}
+	dt := d.DateTime
+	if !dt.Synced || dt.Zone.String() != "61" {
+		t.Errorf("synced %t, zone %s", dt.Synced, dt.Zone.String())
+	}
+	if off := dt.Offset(now); off != 0 {
+		t.Errorf("offset %v, want 0", off)
+	}
+	if got := dt.In(loc); !got.Equal(now) {
+		t.Errorf("adapter time %v, want %v", got, now)
+	}
+	if off := dt.Offset(now.UTC()); off != 9*time.Hour {
+		t.Errorf("offset to UTC %v, want 9h", off)
+	}
+}
+
+func TestSetClockZone(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+
+	d := newDaikin(s.Address())
+	for _, zone := range []string{"Europe/Berlin", "-1", "+01:00"} {
+		if err := d.SetClock(context.Background(), time.Now(), zone); err == nil {
+			t.Errorf("zone %q accepted", zone)
+		}
+	}
+	if len(s.Requests()) > 0 {
+		t.Errorf("requests sent: %v", s.Requests())
+	}
+	if err := daikin.CheckClockZone(""); err != nil {
+		t.Errorf("empty zone: %v", err)
+	}
+}
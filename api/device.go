@@ -0,0 +1,353 @@
+package daikin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Device is a controllable Daikin unit. Daikin implements it for all
+// supported adapter families, other implementations (e.g. mocks) can be
+// plugged into a DaikinNetwork with FactoryOption.
+type Device interface {
+	// Target returns the address of the unit.
+	Target() string
+	// Refresh fetches the current state of the unit. The state is
+	// returned even if some sections failed.
+	Refresh(ctx context.Context) (State, error)
+	// Snapshot returns a copy of the state fetched by the last Refresh.
+	Snapshot() Snapshot
+	// SetControl writes the control settings to the unit.
+	SetControl(ctx context.Context, c *ControlInfo) error
+	// SetZoneStates writes the zone settings to the unit.
+	SetZoneStates(ctx context.Context, z *Zones) error
+	// SetClock sets the clock of the adapter to t and, if not
+	// empty, the time zone index.
+	SetClock(ctx context.Context, t time.Time, zone string) error
+	// Capabilities reports the features supported by the unit.
+	Capabilities() Capabilities
+}
+
+// Snapshot is a copy of the state of a unit. Sections the unit does not
+// support are nil.
+type Snapshot struct {
+	BasicInfo   *BasicInfo
+	ControlInfo *ControlInfo
+	SensorInfo  *SensorInfo
+	PowerInfo   *PowerInfo
+	Zones       *Zones
+	ModelInfo   *ModelInfo
+}
+
+func (s *Snapshot) String() string {
+	return s.Format(Celsius)
+}
+
+// Format is like String, but prints the temperatures in unit.
+func (s *Snapshot) Format(unit TemperatureUnit) string {
+	var ret string
+	if s.BasicInfo != nil {
+		ret = ret + s.BasicInfo.String() + "\n"
+	}
+	if s.ControlInfo != nil {
+		ret = ret + s.ControlInfo.Format(unit) + "\n"
+	}
+	if s.SensorInfo != nil {
+		ret = ret + s.SensorInfo.Format(unit) + "\n"
+	}
+	if s.PowerInfo != nil {
+		ret = ret + s.PowerInfo.String() + "\n"
+	}
+	if s.Zones != nil {
+		ret = ret + s.Zones.String() + "\n"
+	}
+	return ret
+}
+
+// State is the state of a unit returned by Refresh, with the time each
+// section was fetched and the errors of the sections which could not be
+// fetched. It contains copies of the sections, so it does not change
+// with later requests and can be shared between goroutines.
+type State struct {
+	Snapshot
+	fetched [numSections]time.Time
+	errs    [numSections]error
+}
+
+// NewState returns the state of a unit with all sections of s fetched
+// at the time t and the errors of the failed sections. It allows other
+// Device implementations to return a State from Refresh.
+func NewState(s Snapshot, t time.Time, errs map[Section]error) State {
+	st := State{Snapshot: s}
+	for sec := Section(0); sec < numSections; sec++ {
+		if s.present(sec) {
+			st.fetched[sec] = t
+		}
+	}
+	for sec, err := range errs {
+		if sec >= 0 && sec < numSections {
+			st.errs[sec] = err
+		}
+	}
+	return st
+}
+
+// present returns true if the snapshot contains the section.
+func (s *Snapshot) present(sec Section) bool {
+	switch sec {
+	case SectionBasicInfo:
+		return s.BasicInfo != nil
+	case SectionControlInfo:
+		return s.ControlInfo != nil
+	case SectionSensorInfo:
+		return s.SensorInfo != nil
+	case SectionPowerInfo:
+		return s.PowerInfo != nil
+	case SectionZones:
+		return s.Zones != nil
+	case SectionModelInfo:
+		return s.ModelInfo != nil
+	}
+	return false
+}
+
+// Fetched returns when the section was fetched from the unit. The time
+// is zero if it was never fetched. Sections taken from the cache have
+// the time of the request which fetched them.
+func (s *State) Fetched(sec Section) time.Time {
+	if sec < 0 || sec >= numSections {
+		return time.Time{}
+	}
+	return s.fetched[sec]
+}
+
+// Err returns the error of fetching the section, ErrNotSupported if the
+// unit does not have it and nil if the section was fetched. If the basic
+// info failed, the other sections return ErrNotFetched.
+func (s *State) Err(sec Section) error {
+	if sec < 0 || sec >= numSections {
+		return nil
+	}
+	return s.errs[sec]
+}
+
+// ErrNotFetched is the error of the sections, which were not fetched
+// because the basic info failed.
+var ErrNotFetched = errors.New("not fetched")
+
+// err returns the errors of the sections. Only optional sections may
+// be unsupported, the other sections are required.
+func (s *State) err() error {
+	var errs []error
+	for sec, err := range s.errs {
+		if err == nil || errors.Is(err, ErrNotFetched) {
+			continue
+		}
+		if errors.Is(err, ErrNotSupported) && Section(sec).Optional() {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", Section(sec), err))
+	}
+	return errors.Join(errs...)
+}
+
+// Capabilities describes the features supported by a unit.
+type Capabilities struct {
+	// Protocol is the protocol family of the adapter.
+	Protocol Protocol
+	// Humidity is true if the target humidity can be set.
+	Humidity bool
+	// FanRate is true if the fan speed can be set.
+	FanRate bool
+	// FanDir is true if the fan louvre can be set.
+	FanDir bool
+	// PowerInfo is true if the unit reports the power consumption.
+	PowerInfo bool
+	// Zones is true if the unit has zones.
+	Zones bool
+	// Clock is true if the adapter clock can be set.
+	Clock bool
+}
+
+func (c *Capabilities) String() string {
+	return fmt.Sprintf("Protocol: %s\nHumidity control: %t\nFan speed control: %t\nFan louvre control: %t\nPower consumption: %t\nZones: %t\nClock: %t",
+		c.Protocol.String(), c.Humidity, c.FanRate, c.FanDir, c.PowerInfo, c.Zones, c.Clock)
+}
+
+// Target returns the address of the unit.
+func (d *Daikin) Target() string {
+	return d.Address
+}
+
+// Refresh fetches the basic, control, sensor and power info and the
+// zones of the unit. The model info is only fetched once. After the
+// basic info, which detects the protocol of the unit, the sections are
+// fetched concurrently, the request policy of the unit still applies.
+// The returned state contains the error of every section, sections
+// which failed keep the values of the previous fetch. The error is
+// non-nil if the basic, control or sensor info could not be fetched or
+// an optional section supported by the unit failed.
+func (d *Daikin) Refresh(ctx context.Context) (State, error) {
+	d.refresh.Lock()
+	defer d.refresh.Unlock()
+
+	var errs [numSections]error
+	if errs[SectionBasicInfo] = d.getBasicInfo(ctx); errs[SectionBasicInfo] == nil {
+		get := map[Section]func(context.Context) error{
+			SectionControlInfo: d.getControlInfo,
+			SectionSensorInfo:  d.getSensorInfo,
+			SectionPowerInfo:   d.getPowerInfo,
+			SectionZones:       d.getZones,
+		}
+		if !d.present(SectionModelInfo) {
+			get[SectionModelInfo] = d.getModelInfo
+		}
+		var wg sync.WaitGroup
+		for s, f := range get {
+			wg.Add(1)
+			go func(s Section, f func(context.Context) error) {
+				defer wg.Done()
+				errs[s] = f(ctx)
+			}(s, f)
+		}
+		wg.Wait()
+	} else {
+		for s := SectionControlInfo; s < numSections; s++ {
+			errs[s] = ErrNotFetched
+		}
+	}
+
+	d.mu.Lock()
+	if errors.Is(errs[SectionZones], ErrNotSupported) {
+		d.Zones = nil
+	}
+	d.mu.Unlock()
+
+	st := State{Snapshot: d.Snapshot(), fetched: d.updated(), errs: errs}
+	return st, st.err()
+}
+
+// Snapshot returns a copy of the state fetched by the last Refresh.
+func (d *Daikin) Snapshot() Snapshot {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var s Snapshot
+	if d.BasicInfo != nil {
+		b := *d.BasicInfo
+		s.BasicInfo = &b
+	}
+	if d.ControlInfo != nil {
+		c := *d.ControlInfo
+		s.ControlInfo = &c
+	}
+	if d.SensorInfo != nil {
+		se := *d.SensorInfo
+		s.SensorInfo = &se
+	}
+	if d.PowerInfo != nil {
+		p := *d.PowerInfo
+		p.Week = append([]KWattHours(nil), d.PowerInfo.Week...)
+		s.PowerInfo = &p
+	}
+	if d.Zones != nil {
+		z := *d.Zones
+		z.Zones = append([]Zone(nil), d.Zones.Zones...)
+		s.Zones = &z
+	}
+	if d.ModelInfo != nil {
+		m := *d.ModelInfo
+		s.ModelInfo = &m
+	}
+	return s
+}
+
+// modelInfo returns the model info fetched by Refresh, nil if the unit
+// has none.
+func (d *Daikin) modelInfo() *ModelInfo {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.ModelInfo
+}
+
+// SetControl writes the control settings c to the unit. On success,
+// ControlInfo is set to a copy of c. The settings which differ from the
+// settings last read from the unit are validated against the model info
+// before c is sent, see ValidateChange. The cached control info is
+// invalidated, so the next Refresh reads back the settings applied by
+// the unit.
+func (d *Daikin) SetControl(ctx context.Context, c *ControlInfo) error {
+	if c == nil {
+		return fmt.Errorf("no control settings")
+	}
+	if err := c.ValidateChange(d.reportedControl(), d.modelInfo()); err != nil {
+		return err
+	}
+	d.Invalidate(SectionControlInfo)
+	if err := d.store(ctx, uriSetControlInfo, c.urlValues()); err != nil {
+		return err
+	}
+	d.setReported(c)
+	ci := *c
+	d.mu.Lock()
+	d.ControlInfo = &ci
+	d.mu.Unlock()
+	return nil
+}
+
+// SetZoneStates writes the zone settings z to the unit. On success,
+// Zones is set to a copy of z.
+func (d *Daikin) SetZoneStates(ctx context.Context, z *Zones) error {
+	if d.Protocol != ProtocolAirBase {
+		return fmt.Errorf("%s: %w", uriSetZoneSetting, ErrNotSupported)
+	}
+	if z == nil {
+		return fmt.Errorf("no zone settings")
+	}
+	d.Invalidate(SectionZones)
+	if err := d.store(ctx, uriSetZoneSetting, z.urlValues()); err != nil {
+		return err
+	}
+	zs := *z
+	zs.Zones = append([]Zone(nil), z.Zones...)
+	d.mu.Lock()
+	d.Zones = &zs
+	d.mu.Unlock()
+	return nil
+}
+
+// SetClock sets the clock of the Wifi adapter to the wall clock time
+// of t. If zone is not empty, the time zone index of the adapter is
+// set, too, see CheckClockZone.
+func (d *Daikin) SetClock(ctx context.Context, t time.Time, zone string) error {
+	if d.Protocol == ProtocolDsiot {
+		return fmt.Errorf("%s: %w", uriSetDateTime, ErrNotSupported)
+	}
+	if err := CheckClockZone(zone); err != nil {
+		return err
+	}
+	return d.store(ctx, uriSetDateTime, dateTimeUrlValues(t, zone))
+}
+
+// Capabilities reports the features supported by the unit, based on
+// the protocol and the model info fetched by Refresh.
+func (d *Daikin) Capabilities() Capabilities {
+	c := Capabilities{
+		Protocol:  d.Protocol,
+		FanRate:   true,
+		FanDir:    d.Protocol != ProtocolAirBase,
+		PowerInfo: d.Protocol != ProtocolAirBase,
+		Zones:     d.Protocol == ProtocolAirBase,
+		Clock:     d.Protocol != ProtocolDsiot,
+	}
+	if m := d.modelInfo(); m != nil {
+		c.Humidity = m.Humidity
+		// like the validation, a missing en_frate or en_fdir means
+		// supported
+		c.FanRate = m.fanRateSupported()
+		c.FanDir = m.fanDirSupported()
+	}
+	return c
+}
@@ -0,0 +1,97 @@
+package daikin_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+func TestRefresh(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+
+	d := newDaikin(s.Address())
+	st, err := d.Refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the fake has no zones and no week power
+	if !errors.Is(st.Err(daikin.SectionZones), daikin.ErrNotSupported) {
+		t.Errorf("zones: got %v, want ErrNotSupported", st.Err(daikin.SectionZones))
+	}
+	for _, sec := range []daikin.Section{daikin.SectionBasicInfo, daikin.SectionControlInfo, daikin.SectionSensorInfo, daikin.SectionPowerInfo} {
+		if err := st.Err(sec); err != nil || st.Fetched(sec).IsZero() {
+			t.Errorf("%s: err %v, fetched %v", sec, err, st.Fetched(sec))
+		}
+	}
+}
+
+func TestRefreshRequiredSection(t *testing.T) {
+	for _, path := range []string{"/aircon/get_control_info", "/aircon/get_sensor_info"} {
+		s := daikintest.NewServer()
+		s.Delete(path)
+
+		d := newDaikin(s.Address())
+		st, err := d.Refresh(context.Background())
+		if !errors.Is(err, daikin.ErrNotSupported) {
+			t.Errorf("%s missing: got %v, want ErrNotSupported", path, err)
+		}
+		if st.BasicInfo == nil {
+			t.Errorf("%s missing: basic info not returned", path)
+		}
+		s.Close()
+	}
+}
+
+func TestRefreshBasicInfoFailed(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+
+	d := newDaikin(s.Address())
+	s.Fail(testPolicy.Retries + 1)
+	st, err := d.Refresh(context.Background())
+	if err == nil {
+		t.Fatal("Refresh succeeded with failing basic info")
+	}
+	if !errors.Is(st.Err(daikin.SectionControlInfo), daikin.ErrNotFetched) {
+		t.Errorf("control info: got %v, want ErrNotFetched", st.Err(daikin.SectionControlInfo))
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	const path = "/aircon/get_model_info"
+	tests := []struct {
+		model           map[string]string
+		fanRate, fanDir bool
+	}{
+		{map[string]string{"en_frate": "1", "en_fdir": "1"}, true, true},
+		{map[string]string{"en_frate": "0", "en_fdir": "0"}, false, false},
+		// a unit not reporting the flags can set both
+		{map[string]string{"model": "NOTSUPPORT"}, true, true},
+		{map[string]string{"en_frate": "0"}, false, true},
+		{map[string]string{"en_fdir": "0"}, true, false},
+	}
+	for _, tt := range tests {
+		s := daikintest.NewServer()
+		s.Delete(path)
+		setValues(s, path, tt.model)
+		d := newDaikin(s.Address())
+		if _, err := d.Refresh(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		c := d.Capabilities()
+		if c.FanRate != tt.fanRate || c.FanDir != tt.fanDir {
+			t.Errorf("%v: got fan rate %t, louvre %t", tt.model, c.FanRate, c.FanDir)
+		}
+		// the louvre validation agrees
+		ci := *d.ControlInfo
+		ci.FanDir = daikin.FanDirVertical
+		if err := ci.Validate(d.ModelInfo); (err == nil) != tt.fanDir {
+			t.Errorf("%v: louvre validation: %v", tt.model, err)
+		}
+		s.Close()
+	}
+}
@@ -0,0 +1,98 @@
+package daikin
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// FieldChange is a value which differs between two states of a unit.
+type FieldChange struct {
+	// Section is the section of the value.
+	Section Section
+	// Field is the key of the value, e.g. "mode" or "stemp".
+	Field string
+	// Zone is the 1-based number of the zone for zone changes.
+	Zone int
+	// Old and New are the values, e.g. a *Mode or *Temperature.
+	Old Parameter
+	New Parameter
+}
+
+func (c FieldChange) String() string {
+	field := c.Field
+	if c.Zone > 0 {
+		field = fmt.Sprintf("%s[%d]", c.Field, c.Zone)
+	}
+	return fmt.Sprintf("%s %s: %s → %s", c.Section.String(), field, c.Old.String(), c.New.String())
+}
+
+// Diff returns the values which differ between from and to, in the
+// order of the sections and fields. Values are equal if the unit
+// reported the same value. Sections missing in one of the states are
+// skipped.
+func Diff(from Snapshot, to Snapshot) []FieldChange {
+	var changes []FieldChange
+	if from.BasicInfo != nil && to.BasicInfo != nil {
+		changes = diffParameters(changes, SectionBasicInfo, from.BasicInfo, to.BasicInfo)
+	}
+	if from.ControlInfo != nil && to.ControlInfo != nil {
+		changes = diffParameters(changes, SectionControlInfo, from.ControlInfo, to.ControlInfo)
+	}
+	if from.SensorInfo != nil && to.SensorInfo != nil {
+		changes = diffParameters(changes, SectionSensorInfo, from.SensorInfo, to.SensorInfo)
+	}
+	if from.PowerInfo != nil && to.PowerInfo != nil {
+		changes = diffValue(changes, SectionPowerInfo, "curr_day_cool", 0, &from.PowerInfo.DayCool, &to.PowerInfo.DayCool)
+		changes = diffValue(changes, SectionPowerInfo, "curr_day_heat", 0, &from.PowerInfo.DayHeat, &to.PowerInfo.DayHeat)
+	}
+	if from.Zones != nil && to.Zones != nil {
+		for i := range to.Zones.Zones {
+			if i >= len(from.Zones.Zones) {
+				break
+			}
+			changes = diffValue(changes, SectionZones, "zone_onoff", i+1,
+				&from.Zones.Zones[i].Power, &to.Zones.Zones[i].Power)
+		}
+	}
+	if from.ModelInfo != nil && to.ModelInfo != nil {
+		changes = diffParameters(changes, SectionModelInfo, from.ModelInfo, to.ModelInfo)
+	}
+	return changes
+}
+
+// diffParameters appends the changes of the Parameter fields of the
+// info structs pointed to by a and b.
+func diffParameters(changes []FieldChange, s Section, a interface{}, b interface{}) []FieldChange {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for _, f := range tableOf(va.Type()).fields {
+		if f.kind != kindParameter {
+			continue
+		}
+		pa := va.Field(f.index).Addr().Interface().(Parameter)
+		pb := vb.Field(f.index).Addr().Interface().(Parameter)
+		changes = diffValue(changes, s, f.key, 0, pa, pb)
+	}
+	return changes
+}
+
+// diffValue appends a change with copies of a and b if they differ.
+func diffValue(changes []FieldChange, s Section, field string, zone int, a Parameter, b Parameter) []FieldChange {
+	if a.encode() == b.encode() {
+		return changes
+	}
+	return append(changes, FieldChange{
+		Section: s,
+		Field:   field,
+		Zone:    zone,
+		Old:     copyParameter(a),
+		New:     copyParameter(b),
+	})
+}
+
+// copyParameter returns a pointer to a copy of the value p points to.
+func copyParameter(p Parameter) Parameter {
+	v := reflect.ValueOf(p).Elem()
+	c := reflect.New(v.Type())
+	c.Elem().Set(v)
+	return c.Interface().(Parameter)
+}
@@ -0,0 +1,188 @@
+package daikin_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+func control(mode daikin.Mode, stemp float64) *daikin.ControlInfo {
+	return &daikin.ControlInfo{
+		Power:       daikin.PowerOn,
+		Mode:        mode,
+		Fan:         daikin.FanAuto,
+		Temperature: daikin.NewTemperature(stemp, daikin.Celsius),
+	}
+}
+
+func zones(power ...daikin.Power) *daikin.Zones {
+	z := &daikin.Zones{}
+	for i, p := range power {
+		z.Zones = append(z.Zones, daikin.Zone{Name: string(rune('A' + i)), Power: p})
+	}
+	return z
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from daikin.Snapshot
+		to   daikin.Snapshot
+		want []string
+	}{
+		{"unchanged",
+			daikin.Snapshot{ControlInfo: control(daikin.ModeCool, 22)},
+			daikin.Snapshot{ControlInfo: control(daikin.ModeCool, 22)},
+			nil},
+		{"mode",
+			daikin.Snapshot{ControlInfo: control(daikin.ModeHeat, 22)},
+			daikin.Snapshot{ControlInfo: control(daikin.ModeCool, 22)},
+			[]string{"control_info mode: Heat → Cool"}},
+		{"mode and stemp",
+			daikin.Snapshot{ControlInfo: control(daikin.ModeHeat, 21)},
+			daikin.Snapshot{ControlInfo: control(daikin.ModeCool, 25.5)},
+			[]string{"control_info mode: Heat → Cool", "control_info stemp: 21.0 → 25.5"}},
+		{"zone",
+			daikin.Snapshot{Zones: zones(daikin.PowerOn, daikin.PowerOff)},
+			daikin.Snapshot{Zones: zones(daikin.PowerOn, daikin.PowerOn)},
+			[]string{"zones zone_onoff[2]: Off → On"}},
+		{"zone added",
+			daikin.Snapshot{Zones: zones(daikin.PowerOn)},
+			daikin.Snapshot{Zones: zones(daikin.PowerOn, daikin.PowerOn)},
+			nil},
+		{"missing section",
+			daikin.Snapshot{ControlInfo: control(daikin.ModeHeat, 22)},
+			daikin.Snapshot{SensorInfo: &daikin.SensorInfo{}},
+			nil},
+		{"missing in from",
+			daikin.Snapshot{},
+			daikin.Snapshot{ControlInfo: control(daikin.ModeCool, 22)},
+			nil},
+	}
+	for _, tt := range tests {
+		changes := daikin.Diff(tt.from, tt.to)
+		if len(changes) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, changes, tt.want)
+			continue
+		}
+		for i, c := range changes {
+			if c.String() != tt.want[i] {
+				t.Errorf("%s: got %q, want %q", tt.name, c.String(), tt.want[i])
+			}
+		}
+	}
+}
+
+// nextEvent returns the next event of ch or fails after a timeout.
+func nextEvent(t *testing.T, ch <-chan daikin.Event) daikin.Event {
+	t.Helper()
+	select {
+	case ev, ok := <-ch:
+		if !ok {
+			t.Fatal("channel closed")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+	return daikin.Event{}
+}
+
+// changedFields returns the fields of the events of ch until want
+// fields changed. The fake may be polled between two changes.
+func changedFields(t *testing.T, ch <-chan daikin.Event, want int) map[string]daikin.Event {
+	t.Helper()
+	fields := map[string]daikin.Event{}
+	for len(fields) < want {
+		ev := nextEvent(t, ch)
+		if ev.Err != nil {
+			t.Fatal(ev.Err)
+		}
+		for _, c := range ev.Changes {
+			fields[c.Section.String()+" "+c.Field] = ev
+		}
+	}
+	return fields
+}
+
+// settled waits until the fake got the requests of the first poll.
+func settled(t *testing.T, s *daikintest.Server, n int) {
+	t.Helper()
+	for i := 0; i < 500 && count(s, "/aircon/get_control_info") < n; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+
+	d := newDaikin(s.Address())
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := d.Watch(ctx, 20*time.Millisecond)
+	settled(t, s, 1)
+
+	// sensor readings are not watched by default
+	s.Set("/aircon/get_sensor_info", "htemp", "25.0")
+	s.Set("/aircon/get_control_info", "mode", "4")
+	s.Set("/aircon/get_control_info", "stemp", "20.0")
+	fields := changedFields(t, ch, 2)
+	ev, ok := fields["control_info stemp"]
+	if len(fields) != 2 || !ok || fields["control_info mode"].Target != s.Address() ||
+		ev.State.ControlInfo.Mode != daikin.ModeHeat {
+		t.Errorf("got %v", fields)
+	}
+
+	cancel()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("channel not closed")
+		}
+	}
+}
+
+func TestWatchSections(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+
+	d := newDaikin(s.Address())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := d.Watch(ctx, 20*time.Millisecond, daikin.SectionSensorInfo)
+	settled(t, s, 1)
+
+	s.Set("/aircon/get_control_info", "mode", "4")
+	s.Set("/aircon/get_sensor_info", "htemp", "25.0")
+	fields := changedFields(t, ch, 1)
+	if _, ok := fields["sensor_info htemp"]; len(fields) != 1 || !ok {
+		t.Errorf("got %v", fields)
+	}
+}
+
+func TestNetworkWatch(t *testing.T) {
+	fakes := newFakes(t, 2)
+	d := newNetwork(t, []string{fakes[0].Address(), fakes[1].Address()})
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := d.Watch(ctx, 20*time.Millisecond)
+	settled(t, fakes[0], 1)
+	settled(t, fakes[1], 1)
+
+	fakes[1].Set("/aircon/get_control_info", "pow", "1")
+	ev := nextEvent(t, ch)
+	if ev.Target != fakes[1].Address() || len(ev.Changes) != 1 || ev.Changes[0].Field != "pow" {
+		t.Errorf("got %+v", ev)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
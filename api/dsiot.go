@@ -0,0 +1,461 @@
+package daikin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// The BRP084 adapters and firmware 2.8+ replace the key=value endpoints
+// with a JSON oneM2M-style interface: all requests are POSTed as a batch
+// to /dsiot/multireq and address resources of a nested tree. The tree of
+// the indoor unit is below adr_0100, the outdoor unit below adr_0200:
+//
+//	dgc_status
+//	  e_1002        indoor unit functions
+//	    e_A002      p_01: power
+//	    e_3001      p_01: mode, p_02..: setpoint, fan and louvre per mode
+//	    e_A00B      p_01: room temperature, p_02: room humidity
+//	  e_1003        outdoor unit functions
+//	    e_A00D      p_01: outside temperature
+//
+// The energy history is below adr_0100.i_power: week_power has the
+// consumption of the last days in Wh, day_power_ex the hourly
+// consumption of today and yesterday per heating and cooling in 0.1 kWh
+// like get_day_power_ex.
+//
+// The backend translates this tree into the BRP key/value representation
+// understood by the populate methods.
+
+const (
+	uriDsiotMultiReq = "/dsiot/multireq"
+
+	dsiotAdapterInfo  = "/dsiot/edge.adp_i"
+	dsiotIndoorStatus = "/dsiot/edge/adr_0100.dgc_status"
+	dsiotOutdoorStat  = "/dsiot/edge/adr_0200.dgc_status"
+	dsiotWeekPower    = "/dsiot/edge/adr_0100.i_power.week_power"
+	dsiotDayPower     = "/dsiot/edge/adr_0100.i_power.day_power_ex"
+
+	dsiotOpGet    = 2
+	dsiotOpUpdate = 3
+
+	// response status code for success
+	dsiotRscOk      = 2000
+	dsiotRscUpdated = 2004
+	// response status code of unknown resources
+	dsiotRscNotFound = 4004
+)
+
+type dsiotRequest struct {
+	Op int        `json:"op"`
+	To string     `json:"to"`
+	Pc *dsiotNode `json:"pc,omitempty"`
+}
+
+type dsiotResponse struct {
+	Fr  string     `json:"fr"`
+	Pc  *dsiotNode `json:"pc"`
+	Rsc int        `json:"rsc"`
+}
+
+type dsiotNode struct {
+	Pn  string          `json:"pn"`
+	Pt  int             `json:"pt,omitempty"`
+	Pv  json.RawMessage `json:"pv,omitempty"`
+	Pch []*dsiotNode    `json:"pch,omitempty"`
+}
+
+// find returns the node below n following the path of pn names.
+func (n *dsiotNode) find(path ...string) *dsiotNode {
+	if n == nil || len(path) == 0 {
+		return n
+	}
+	for _, c := range n.Pch {
+		if c.Pn == path[0] {
+			return c.find(path[1:]...)
+		}
+	}
+	return nil
+}
+
+// value returns the value of the node below n as string.
+func (n *dsiotNode) value(path ...string) (string, bool) {
+	c := n.find(path...)
+	if c == nil || len(c.Pv) == 0 {
+		return "", false
+	}
+	var s string
+	if err := json.Unmarshal(c.Pv, &s); err == nil {
+		return s, true
+	}
+	return string(c.Pv), true
+}
+
+// values returns the array value of the node below n.
+func (n *dsiotNode) values(path ...string) ([]json.Number, bool) {
+	c := n.find(path...)
+	if c == nil {
+		return nil, false
+	}
+	var v []json.Number
+	if err := json.Unmarshal(c.Pv, &v); err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// dsiotTree builds the nested update tree for a single resource value.
+func dsiotTree(value string, path ...string) *dsiotNode {
+	n := &dsiotNode{Pn: path[len(path)-1]}
+	pv, _ := json.Marshal(value)
+	n.Pv = pv
+	for i := len(path) - 2; i >= 0; i-- {
+		n = &dsiotNode{Pn: path[i], Pch: []*dsiotNode{n}}
+	}
+	return n
+}
+
+// merge adds the children of o to n.
+func (n *dsiotNode) merge(o *dsiotNode) {
+	for _, oc := range o.Pch {
+		if c := n.find(oc.Pn); c != nil && len(oc.Pch) > 0 {
+			c.merge(oc)
+			continue
+		}
+		n.Pch = append(n.Pch, oc)
+	}
+}
+
+// The per mode settings below e_1002/e_3001: setpoint, fan speed,
+// vertical and horizontal swing.
+type dsiotModeParams struct {
+	mode   string
+	temp   string
+	fan    string
+	swingV string
+	swingH string
+}
+
+var dsiotModes = []dsiotModeParams{
+	{mode: "0000", temp: "", fan: "p_28", swingV: "p_24", swingH: "p_25"},     // Fan
+	{mode: "0100", temp: "p_03", fan: "p_0A", swingV: "p_07", swingH: "p_08"}, // Heat
+	{mode: "0200", temp: "p_02", fan: "p_09", swingV: "p_05", swingH: "p_06"}, // Cool
+	{mode: "0300", temp: "p_1D", fan: "p_26", swingV: "p_20", swingH: "p_21"}, // Auto
+	{mode: "0500", temp: "", fan: "p_27", swingV: "p_22", swingH: "p_23"},     // Dehumidify
+}
+
+var dsiotModeMap = map[string]string{
+	"0000": "6",
+	"0100": "4",
+	"0200": "3",
+	"0300": "0",
+	"0500": "2",
+}
+
+var dsiotFanMap = map[string]string{
+	"0A00": "A",
+	"0B00": "B",
+	"0300": "3",
+	"0400": "4",
+	"0500": "5",
+	"0600": "6",
+	"0700": "7",
+}
+
+const (
+	dsiotSwingOff = "000000"
+	dsiotSwingOn  = "0F0000"
+)
+
+func dsiotModeParamsFor(mode string) (dsiotModeParams, bool) {
+	for _, m := range dsiotModes {
+		if m.mode == mode {
+			return m, true
+		}
+	}
+	return dsiotModeParams{}, false
+}
+
+// lookup returns the key of value v in m.
+func lookup(m map[string]string, v string) (string, bool) {
+	for k, mv := range m {
+		if mv == v {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// dsiotTemp decodes a temperature in 0.5 degree steps.
+func dsiotTemp(hex string) (string, error) {
+	v, err := strconv.ParseInt(hex, 16, 16)
+	if err != nil {
+		return "", err
+	}
+	// signed byte
+	if len(hex) <= 2 && v > 127 {
+		v = v - 256
+	}
+	return strconv.FormatFloat(float64(v)/2, 'f', 1, 64), nil
+}
+
+// dsiotInt decodes an integer value, e.g. the room temperature.
+func dsiotInt(hex string) (string, error) {
+	v, err := strconv.ParseInt(hex, 16, 16)
+	if err != nil {
+		return "", err
+	}
+	if len(hex) <= 2 && v > 127 {
+		v = v - 256
+	}
+	return strconv.FormatInt(v, 10), nil
+}
+
+// multiReq sends the batch of requests to the unit. Only idempotent
+// batches are retried.
+func (d *Daikin) multiReq(ctx context.Context, idempotent bool, reqs []dsiotRequest) ([]dsiotResponse, error) {
+	body, err := json.Marshal(struct {
+		Requests []dsiotRequest `json:"requests"`
+	}{reqs})
+	if err != nil {
+		return nil, err
+	}
+	resp, data, err := d.exchange(ctx, idempotent, func() (*http.Request, error) {
+		req, err := d.newRequest(ctx, http.MethodPost, uriDsiotMultiReq, "", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", uriDsiotMultiReq, ErrNotSupported)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: device returned HTTP status %s",
+			uriDsiotMultiReq, resp.Status)
+	}
+	var r struct {
+		Responses []dsiotResponse `json:"responses"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("%s: invalid response: %v", uriDsiotMultiReq, err)
+	}
+	if len(r.Responses) != len(reqs) {
+		return nil, fmt.Errorf("%s: got %d responses for %d requests",
+			uriDsiotMultiReq, len(r.Responses), len(reqs))
+	}
+	for i := range r.Responses {
+		rsc := r.Responses[i].Rsc
+		if rsc == dsiotRscNotFound {
+			return nil, fmt.Errorf("%s: %w", reqs[i].To, ErrNotSupported)
+		}
+		if rsc != dsiotRscOk && rsc != dsiotRscUpdated {
+			return nil, fmt.Errorf("%s: device returned error rsc=%d", reqs[i].To, rsc)
+		}
+	}
+	return r.Responses, nil
+}
+
+// dsiotGet reads the resources and returns their trees.
+func (d *Daikin) dsiotGet(ctx context.Context, to ...string) ([]*dsiotNode, error) {
+	reqs := make([]dsiotRequest, len(to))
+	for i := range to {
+		reqs[i] = dsiotRequest{Op: dsiotOpGet, To: to[i] + "?filter=pv,md"}
+	}
+	resps, err := d.multiReq(ctx, true, reqs)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*dsiotNode, len(resps))
+	for i := range resps {
+		nodes[i] = resps[i].Pc
+	}
+	return nodes, nil
+}
+
+// dsiotFetch queries the resources matching the BRP endpoint uri and
+// returns the values in BRP representation.
+func (d *Daikin) dsiotFetch(ctx context.Context, uri string) (map[string]string, error) {
+	values := map[string]string{"ret": returnOk}
+	switch uri {
+	case uriGetBasicInfo:
+		nodes, err := d.dsiotGet(ctx, dsiotAdapterInfo)
+		if err != nil {
+			return nil, err
+		}
+		values["type"] = "aircon"
+		if v, ok := nodes[0].value("name"); ok {
+			values["name"] = url.PathEscape(v)
+		}
+		if v, ok := nodes[0].value("ver"); ok {
+			values["ver"] = v
+		}
+		if v, ok := nodes[0].value("rev"); ok {
+			values["rev"] = v
+		}
+		if v, ok := nodes[0].value("mac"); ok {
+			values["mac"] = v
+		}
+	case uriGetControlInfo:
+		nodes, err := d.dsiotGet(ctx, dsiotIndoorStatus)
+		if err != nil {
+			return nil, err
+		}
+		status := nodes[0].find("e_1002")
+		if v, ok := status.value("e_A002", "p_01"); ok {
+			values["pow"] = strings.TrimLeft(v, "0")
+			if len(values["pow"]) == 0 {
+				values["pow"] = "0"
+			}
+		}
+		mode, _ := status.value("e_3001", "p_01")
+		if m, ok := dsiotModeMap[mode]; ok {
+			values["mode"] = m
+		}
+		params, _ := dsiotModeParamsFor(mode)
+		values["stemp"] = "--"
+		if len(params.temp) > 0 {
+			if v, ok := status.value("e_3001", params.temp); ok {
+				t, err := dsiotTemp(v)
+				if err != nil {
+					return nil, fmt.Errorf("stemp: %v", err)
+				}
+				values["stemp"] = t
+			}
+		}
+		// the dsiot units have no humidity control
+		values["shum"] = HumidityNone
+		if v, ok := status.value("e_3001", params.fan); ok {
+			if f, ok := dsiotFanMap[v]; ok {
+				values["f_rate"] = f
+			}
+		}
+		dir := 0
+		if v, ok := status.value("e_3001", params.swingV); ok && v != dsiotSwingOff {
+			dir = dir | int(FanDirVertical)
+		}
+		if v, ok := status.value("e_3001", params.swingH); ok && v != dsiotSwingOff {
+			dir = dir | int(FanDirHorizontal)
+		}
+		values["f_dir"] = strconv.Itoa(dir)
+	case uriGetSensorInfo:
+		nodes, err := d.dsiotGet(ctx, dsiotIndoorStatus, dsiotOutdoorStat)
+		if err != nil {
+			return nil, err
+		}
+		values["htemp"], values["hhum"], values["otemp"] = "--", "--", "--"
+		if v, ok := nodes[0].value("e_1002", "e_A00B", "p_01"); ok {
+			if t, err := dsiotInt(v); err == nil {
+				values["htemp"] = t
+			}
+		}
+		if v, ok := nodes[0].value("e_1002", "e_A00B", "p_02"); ok {
+			if h, err := dsiotInt(v); err == nil {
+				values["hhum"] = h
+			}
+		}
+		if v, ok := nodes[1].value("e_1003", "e_A00D", "p_01"); ok {
+			if t, err := dsiotTemp(v); err == nil {
+				values["otemp"] = t
+			}
+		}
+	case uriGetWeekPower:
+		nodes, err := d.dsiotGet(ctx, dsiotWeekPower)
+		if err != nil {
+			return nil, err
+		}
+		datas, ok := nodes[0].values("datas")
+		if !ok {
+			return nil, fmt.Errorf("%s: no datas in week_power", dsiotWeekPower)
+		}
+		elems := make([]string, len(datas))
+		for i := range datas {
+			elems[i] = datas[i].String()
+		}
+		values["datas"] = strings.Join(elems, "/")
+		if v, ok := nodes[0].value("today_runtime"); ok {
+			values["today_runtime"] = v
+		}
+	case uriGetDayPowerEx:
+		nodes, err := d.dsiotGet(ctx, dsiotDayPower)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range []string{"curr_day_heat", "curr_day_cool", "prev_1day_heat", "prev_1day_cool"} {
+			hours, ok := nodes[0].values(k)
+			if !ok {
+				return nil, fmt.Errorf("%s: no %s in day_power_ex", dsiotDayPower, k)
+			}
+			elems := make([]string, len(hours))
+			for i := range hours {
+				elems[i] = hours[i].String()
+			}
+			values[k] = strings.Join(elems, "/")
+		}
+	default:
+		return nil, fmt.Errorf("%s: %w", uri, ErrNotSupported)
+	}
+	return values, nil
+}
+
+// dsiotStore writes the BRP query string for set_control_info to the
+// resources of the indoor unit.
+func (d *Daikin) dsiotStore(ctx context.Context, uri string, query string) error {
+	if uri != uriSetControlInfo {
+		return fmt.Errorf("%s: %w", uri, ErrNotSupported)
+	}
+	values := map[string]string{}
+	for _, p := range strings.Split(query, "&") {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) == 2 {
+			values[kv[0]] = kv[1]
+		}
+	}
+
+	tree := &dsiotNode{Pn: "dgc_status"}
+	add := func(value string, path ...string) {
+		tree.merge(&dsiotNode{Pch: []*dsiotNode{dsiotTree(value, path...)}})
+	}
+
+	if v, ok := values["pow"]; ok {
+		add("0"+v, "e_1002", "e_A002", "p_01")
+	}
+	mode, ok := lookup(dsiotModeMap, values["mode"])
+	if !ok {
+		// the auto variants of the BRP protocol
+		mode = "0300"
+	}
+	add(mode, "e_1002", "e_3001", "p_01")
+	params, _ := dsiotModeParamsFor(mode)
+	if v, err := strconv.ParseFloat(values["stemp"], 64); err == nil && len(params.temp) > 0 {
+		add(fmt.Sprintf("%02x", int(v*2)), "e_1002", "e_3001", params.temp)
+	}
+	if f, ok := lookup(dsiotFanMap, values["f_rate"]); ok {
+		add(f, "e_1002", "e_3001", params.fan)
+	}
+	if v, err := strconv.Atoi(values["f_dir"]); err == nil {
+		swingV, swingH := dsiotSwingOff, dsiotSwingOff
+		if v&int(FanDirVertical) != 0 {
+			swingV = dsiotSwingOn
+		}
+		if v&int(FanDirHorizontal) != 0 {
+			swingH = dsiotSwingOn
+		}
+		add(swingV, "e_1002", "e_3001", params.swingV)
+		add(swingH, "e_1002", "e_3001", params.swingH)
+	}
+
+	_, err := d.multiReq(ctx, false, []dsiotRequest{
+		{Op: dsiotOpUpdate, To: dsiotIndoorStatus, Pc: tree},
+	})
+	return err
+}
@@ -0,0 +1,92 @@
+package daikin_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+func TestDsiot(t *testing.T) {
+	s := daikintest.NewDsiotServer()
+	defer s.Close()
+
+	d := newDaikin(s.Address())
+	st, err := d.Refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Protocol != daikin.ProtocolDsiot {
+		t.Fatalf("protocol is %s, want dsiot", d.Protocol.String())
+	}
+	if got := st.BasicInfo.Name.String(); got != "Living Room" {
+		t.Errorf("name is %q, want Living Room", got)
+	}
+	c := st.ControlInfo
+	if c.Mode != daikin.ModeCool || c.Temperature.Value() != 23 || c.Fan != daikin.FanAuto {
+		t.Errorf("control info: %s", c.String())
+	}
+	if c.Humidity.IsSet() {
+		t.Errorf("humidity is %s, want unsupported", c.Humidity.String())
+	}
+	if st.SensorInfo.HomeTemperature.Value() != 23 || st.SensorInfo.OutsideTemperature.Value() != 10 {
+		t.Errorf("sensor info: %s", st.SensorInfo.String())
+	}
+	p := st.PowerInfo
+	if p == nil || p.DayCool.String() != "0.6" || p.DayHeat.String() != "0.0" || len(p.Week) != 7 {
+		t.Fatalf("power info: %+v", p)
+	}
+
+	if _, err := d.Apply(context.Background(), daikin.WithPower(daikin.PowerOn), daikin.WithTemperature(21.5)); err != nil {
+		t.Fatal(err)
+	}
+	st, err = d.Refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.ControlInfo.Power != daikin.PowerOn || st.ControlInfo.Temperature.Value() != 21.5 {
+		t.Errorf("control info after Apply: %s", st.ControlInfo.String())
+	}
+}
+
+// Firmware 2.8 of the BRP069 adapters answers /common/basic_info, but
+// only speaks dsiot otherwise.
+func TestDsiotFirmware28(t *testing.T) {
+	s := daikintest.NewDsiotServer()
+	defer s.Close()
+	values := seedValues(t, "brp069_basic_info")
+	values["ver"] = "2_8_0"
+	setValues(s.Server, "/common/basic_info", values)
+
+	d := newDaikin(s.Address())
+	st, err := d.Refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Protocol != daikin.ProtocolDsiot {
+		t.Fatalf("protocol is %s, want dsiot", d.Protocol.String())
+	}
+	if st.ControlInfo == nil || st.ControlInfo.Mode != daikin.ModeCool {
+		t.Errorf("control info: %+v", st.ControlInfo)
+	}
+}
+
+// Firmware 3 of the BRP069 adapters still speaks the key=value protocol.
+func TestBRPFirmware3(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+	s.Set("/common/basic_info", "ver", "3_3_1")
+
+	d := newDaikin(s.Address())
+	st, err := d.Refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Protocol != daikin.ProtocolBRP {
+		t.Errorf("protocol is %s, want BRP", d.Protocol.String())
+	}
+	if st.ControlInfo == nil || st.SensorInfo == nil {
+		t.Errorf("control or sensor info missing: %+v", st.Snapshot)
+	}
+}
@@ -0,0 +1,20 @@
+package daikin
+
+import (
+	"encoding"
+	"encoding/json"
+)
+
+// unmarshalJSONText decodes a JSON string or number with the text
+// unmarshaler of u, so that numeric values are accepted as fallback.
+func unmarshalJSONText(data []byte, u encoding.TextUnmarshaler) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return u.UnmarshalText([]byte(s))
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	return u.UnmarshalText([]byte(n.String()))
+}
@@ -0,0 +1,191 @@
+package daikin_test
+
+import (
+	"encoding"
+	"encoding/json"
+	"testing"
+
+	"github.com/thkukuk/daikin-gomod/api"
+)
+
+// textCase is the input of UnmarshalText or, if it is valid JSON and
+// isJSON is set, json.Unmarshal, and the expected MarshalText and
+// json.Marshal output. An empty text means an error is expected.
+type textCase struct {
+	in     string
+	isJSON bool
+	text   string
+	json   string
+}
+
+// textPointer is the pointer to a value type T implementing the text
+// encoding.
+type textPointer[T any] interface {
+	*T
+	encoding.TextUnmarshaler
+}
+
+// testText checks the round trip of the cases through the text and JSON
+// encoding of T.
+func testText[T encoding.TextMarshaler, P textPointer[T]](t *testing.T, tests []textCase) {
+	t.Helper()
+	for _, tt := range tests {
+		var v T
+		var err error
+		if tt.isJSON {
+			err = json.Unmarshal([]byte(tt.in), P(&v))
+		} else {
+			err = P(&v).UnmarshalText([]byte(tt.in))
+		}
+		if len(tt.text) == 0 {
+			if err == nil {
+				t.Errorf("%T %s: no error", v, tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%T %s: %v", v, tt.in, err)
+			continue
+		}
+		if text, err := v.MarshalText(); err != nil || string(text) != tt.text {
+			t.Errorf("%T %s: MarshalText %q, %v, want %q", v, tt.in, text, err, tt.text)
+		}
+		if data, err := json.Marshal(v); err != nil || string(data) != tt.json {
+			t.Errorf("%T %s: json %s, %v, want %s", v, tt.in, data, err, tt.json)
+		}
+
+		// the marshaled value is read back unchanged
+		var back T
+		if err := P(&back).UnmarshalText([]byte(tt.text)); err != nil {
+			t.Errorf("%T %s: reading back %q: %v", v, tt.in, tt.text, err)
+		} else if text, _ := back.MarshalText(); string(text) != tt.text {
+			t.Errorf("%T %s: read back as %q, want %q", v, tt.in, text, tt.text)
+		}
+	}
+}
+
+func TestModeText(t *testing.T) {
+	testText[daikin.Mode](t, []textCase{
+		{"cool", false, "cool", `"cool"`},
+		{"HEAT", false, "heat", `"heat"`},
+		{"dry", false, "dehumidify", `"dehumidify"`},
+		{"6", false, "fan", `"fan"`},
+		{"4", true, "heat", `"heat"`},
+		{`"auto"`, true, "auto", `"auto"`},
+		{"5", false, "", ""},
+		{"warm", false, "", ""},
+	})
+}
+
+func TestFanText(t *testing.T) {
+	testText[daikin.Fan](t, []textCase{
+		{"auto", false, "auto", `"auto"`},
+		{"Silent", false, "silent", `"silent"`},
+		{"level3", false, "level3", `"level3"`},
+		{"5", false, "level3", `"level3"`},
+		{"A", false, "auto", `"auto"`},
+		{"7", true, "level5", `"level5"`},
+		{`"B"`, true, "silent", `"silent"`},
+		{"level6", false, "", ""},
+		{"8", true, "", ""},
+	})
+}
+
+func TestFanDirText(t *testing.T) {
+	testText[daikin.FanDir](t, []textCase{
+		{"stopped", false, "stopped", `"stopped"`},
+		{"Vertical", false, "vertical", `"vertical"`},
+		{"2", false, "horizontal", `"horizontal"`},
+		{"3", true, "both", `"both"`},
+		{`"both"`, true, "both", `"both"`},
+		{"4", false, "", ""},
+		{"up", false, "", ""},
+	})
+}
+
+func TestPowerText(t *testing.T) {
+	testText[daikin.Power](t, []textCase{
+		{"on", false, "on", `"on"`},
+		{"OFF", false, "off", `"off"`},
+		{"1", false, "on", `"on"`},
+		{"0", true, "off", `"off"`},
+		{`"on"`, true, "on", `"on"`},
+		{"2", false, "", ""},
+		{"true", true, "", ""},
+	})
+}
+
+func TestMarshalUnknownValues(t *testing.T) {
+	for _, tt := range []struct {
+		v    encoding.TextMarshaler
+		want string
+	}{
+		{daikin.Mode(5), "5"},
+		{daikin.Fan("X"), "X"},
+		{daikin.FanDir(9), "9"},
+		{daikin.Power(2), "2"},
+	} {
+		if text, err := tt.v.MarshalText(); err != nil || string(text) != tt.want {
+			t.Errorf("%T: got %q, %v, want %q", tt.v, text, err, tt.want)
+		}
+	}
+}
+
+func TestTemperatureText(t *testing.T) {
+	testText[daikin.Temperature](t, []textCase{
+		{"22.5", false, "22.5", "22.5"},
+		{"18", false, "18", "18"},
+		{"M", false, "M", `"M"`},
+		{"--", false, "--", "null"},
+		{"-", false, "-", "null"},
+		{"21.5", true, "21.5", "21.5"},
+		{`"23.0"`, true, "23", "23"},
+		{`"M"`, true, "M", `"M"`},
+		{"null", true, "--", "null"},
+		{"warm", false, "", ""},
+		{"true", true, "", ""},
+	})
+}
+
+func TestHumidityText(t *testing.T) {
+	testText[daikin.Humidity](t, []textCase{
+		{"50", false, "50", "50"},
+		{"AUTO", false, "AUTO", `"AUTO"`},
+		{"--", false, "--", "null"},
+		{"45", true, "45", "45"},
+		{`"60"`, true, "60", "60"},
+		{`"AUTO"`, true, "AUTO", `"AUTO"`},
+		{"null", true, "--", "null"},
+		{"auto", false, "", ""},
+		{"50.5", true, "", ""},
+	})
+}
+
+func TestKWattHoursText(t *testing.T) {
+	testText[daikin.KWattHours](t, []textCase{
+		{"1.5", false, "1.5", "1.5"},
+		{"12", false, "12", "12"},
+		{"-", false, "-", "null"},
+		{"--", false, "--", "null"},
+		{"0.25", true, "0.25", "0.25"},
+		{`"3.5"`, true, "3.5", "3.5"},
+		{"null", true, "-", "null"},
+		{"many", false, "", ""},
+	})
+}
+
+func TestStringText(t *testing.T) {
+	testText[daikin.Name](t, []textCase{
+		{"Living room", false, "Living room", `"Living room"`},
+		{`"Bed, room"`, true, "Bed, room", `"Bed, room"`},
+		{"1", true, "", ""},
+	})
+	testText[daikin.Version](t, []textCase{
+		{"3.3.6", false, "3.3.6", `"3.3.6"`},
+		{`"1.2.51"`, true, "1.2.51", `"1.2.51"`},
+	})
+	testText[daikin.String](t, []textCase{
+		{"BRP069B41", false, "BRP069B41", `"BRP069B41"`},
+		{`"a=b,c"`, true, "a=b,c", `"a=b,c"`},
+	})
+}
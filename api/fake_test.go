@@ -0,0 +1,49 @@
+package daikin_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+// testPolicy avoids the delays between requests to the fake adapters.
+var testPolicy = daikin.RequestPolicy{Retries: 2}
+
+// newDaikin returns a Daikin for the fake adapter at address.
+func newDaikin(address string) *daikin.Daikin {
+	p := testPolicy
+	return &daikin.Daikin{Address: address, Policy: &p}
+}
+
+// seedValues returns the values of a reply recorded in the tokenizer
+// fuzz corpus.
+func seedValues(t *testing.T, name string) map[string]string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "fuzz", "FuzzTokenizer", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	body, err := strconv.Unquote(strings.TrimSuffix(strings.TrimPrefix(lines[len(lines)-1], "[]byte("), ")"))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	values, err := daikin.ParseValues([]byte(body))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	delete(values, "ret")
+	return values
+}
+
+// setValues replaces the values of the endpoint path of the fake adapter.
+func setValues(s *daikintest.Server, path string, values map[string]string) {
+	for k, v := range values {
+		s.Set(path, k, v)
+	}
+}
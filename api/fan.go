@@ -4,6 +4,7 @@ import (
         "fmt"
 	"math"
         "strconv"
+	"strings"
 )
 
 // Fan is the fan speed of the Daikin unit.
@@ -30,14 +31,29 @@ var fanMap = map[Fan]string{
 	Fan5:      "5",
 }
 
-func (f *Fan) setUrlValues() string {
-	return "f_rate=" + string(*f)
+// fanNames are the symbolic names used for text encoding.
+var fanNames = map[Fan]string{
+	FanAuto:   "auto",
+	FanSilent: "silent",
+	Fan1:      "level1",
+	Fan2:      "level2",
+	Fan3:      "level3",
+	Fan4:      "level4",
+	Fan5:      "level5",
+}
+
+func (f *Fan) encode() string {
+	return string(*f)
+}
+
+func (f *Fan) decode(param string, s string) error {
+	return f.Decode(s)
 }
 
 func (f *Fan) Decode(s string) error {
 	// check if value is supported
 	if _, ok := fanMap[Fan(s)]; !ok {
-		return fmt.Errorf("unknown pwr value: %s", s)
+		return fmt.Errorf("unknown f_rate value: %s", s)
 	}
 	*f = Fan(s)
 
@@ -68,3 +84,29 @@ func (f *Fan) Float64() float64 {
 
 }
 
+// MarshalText returns the symbolic name like "auto" or "level3",
+// unknown values as received from the unit.
+func (f Fan) MarshalText() ([]byte, error) {
+	if v, ok := fanNames[f]; ok {
+		return []byte(v), nil
+	}
+	return []byte(f), nil
+}
+
+// UnmarshalText accepts the symbolic name or the value of the unit
+// (A, B, 3-7).
+func (f *Fan) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	for k, v := range fanNames {
+		if v == s {
+			*f = k
+			return nil
+		}
+	}
+	return f.Decode(string(text))
+}
+
+// UnmarshalJSON accepts a string or a number.
+func (f *Fan) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, f)
+}
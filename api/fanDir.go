@@ -3,6 +3,7 @@ package daikin
 import (
         "fmt"
         "strconv"
+	"strings"
 )
 
 // FanDir is the louvre swing setting of the Daikin unit.
@@ -23,11 +24,11 @@ var fanDirMap = map[FanDir]string{
 	FanDirBoth:       "Both",
 }
 
-func (f *FanDir) setUrlValues() string {
-	return "f_dir=" + strconv.Itoa(int(*f))
+func (f *FanDir) encode() string {
+	return strconv.Itoa(int(*f))
 }
 
-func (f *FanDir) decode(s string) error {
+func (f *FanDir) decode(param string, s string) error {
 	v, err := strconv.Atoi(s)
 	if err != nil {
 		return fmt.Errorf("invalid f_dir value: %s (err=%v)", s, err)
@@ -52,3 +53,27 @@ func (f *FanDir) Float64() float64 {
 	return float64(*f)
 }
 
+// MarshalText returns "stopped", "vertical", "horizontal" or "both",
+// unknown values as number.
+func (f FanDir) MarshalText() ([]byte, error) {
+	if v, ok := fanDirMap[f]; ok {
+		return []byte(strings.ToLower(v)), nil
+	}
+	return []byte(strconv.Itoa(int(f))), nil
+}
+
+// UnmarshalText accepts the symbolic name or the numeric value.
+func (f *FanDir) UnmarshalText(text []byte) error {
+	for k, v := range fanDirMap {
+		if strings.EqualFold(v, string(text)) {
+			*f = k
+			return nil
+		}
+	}
+	return f.decode("f_dir", string(text))
+}
+
+// UnmarshalJSON accepts a string or a number.
+func (f *FanDir) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, f)
+}
@@ -0,0 +1,174 @@
+package daikin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestPolicy controls how requests are sent to a unit. The Wifi
+// adapters fail or even reboot if they get concurrent requests, so only
+// one request per unit is in flight at any time.
+type RequestPolicy struct {
+	// MinGap is the minimum time between the end of a request and the
+	// start of the next one to the same unit.
+	MinGap time.Duration
+	// Retries is the number of retries of failed read requests.
+	Retries int
+	// Backoff is the delay before the first retry. It is doubled with
+	// every further retry and jittered by up to 50%.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between two retries.
+	MaxBackoff time.Duration
+}
+
+// DefaultRequestPolicy is used if Daikin.Policy is nil.
+var DefaultRequestPolicy = RequestPolicy{
+	MinGap:     100 * time.Millisecond,
+	Retries:    3,
+	Backoff:    500 * time.Millisecond,
+	MaxBackoff: 5 * time.Second,
+}
+
+// maxResponseSize limits the size of a reply read from a unit, so that
+// a misbehaving device cannot exhaust the memory.
+const maxResponseSize = 1 << 20
+
+// gate serializes the requests to one unit.
+type gate struct {
+	// sem holds a token while a request is in flight.
+	sem  chan struct{}
+	last time.Time
+}
+
+var (
+	gatesMu sync.Mutex
+	gates   = map[string]*gate{}
+)
+
+// gateFor returns the gate of the unit at address. All Daikin values
+// for the same address share one gate.
+func gateFor(address string) *gate {
+	address = strings.TrimPrefix(address, "http://")
+	address = strings.TrimPrefix(address, "https://")
+
+	gatesMu.Lock()
+	defer gatesMu.Unlock()
+	g, ok := gates[address]
+	if !ok {
+		g = &gate{sem: make(chan struct{}, 1)}
+		gates[address] = g
+	}
+	return g
+}
+
+// acquire waits until no other request is in flight and the minimum
+// gap to the previous request has passed.
+func (g *gate) acquire(ctx context.Context, gap time.Duration) error {
+	select {
+	case g.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if wait := time.Until(g.last.Add(gap)); wait > 0 {
+		if err := sleep(ctx, wait); err != nil {
+			g.release()
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *gate) release() {
+	g.last = time.Now()
+	<-g.sem
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Daikin) policy() *RequestPolicy {
+	if d.Policy != nil {
+		return d.Policy
+	}
+	return &DefaultRequestPolicy
+}
+
+// errRetry marks a failed request which may succeed if repeated.
+var errRetry = errors.New("temporary failure")
+
+// exchange sends the request created by newReq under the gate of the
+// unit and returns the response together with the body. Idempotent
+// requests are retried with jittered backoff on network errors and
+// server errors.
+func (d *Daikin) exchange(ctx context.Context, idempotent bool, newReq func() (*http.Request, error)) (*http.Response, []byte, error) {
+	p := d.policy()
+	g := gateFor(d.Address)
+	backoff := p.Backoff
+
+	for attempt := 0; ; attempt++ {
+		resp, body, err := d.exchangeOnce(ctx, g, p.MinGap, newReq)
+		if err == nil || !errors.Is(err, errRetry) {
+			return resp, body, err
+		}
+		err = errors.Unwrap(err)
+		if !idempotent || attempt >= p.Retries || ctx.Err() != nil {
+			return resp, body, err
+		}
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		if err := sleep(ctx, wait); err != nil {
+			return nil, nil, err
+		}
+		backoff = backoff * 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// retryable wraps err so that exchange repeats the request.
+type retryable struct{ err error }
+
+func (e *retryable) Error() string        { return e.err.Error() }
+func (e *retryable) Unwrap() error        { return e.err }
+func (e *retryable) Is(target error) bool { return target == errRetry }
+
+func (d *Daikin) exchangeOnce(ctx context.Context, g *gate, gap time.Duration, newReq func() (*http.Request, error)) (*http.Response, []byte, error) {
+	if err := g.acquire(ctx, gap); err != nil {
+		return nil, nil, err
+	}
+	defer g.release()
+
+	req, err := newReq()
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := d.client().Do(req)
+	if err != nil {
+		return nil, nil, &retryable{err}
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, nil, &retryable{err}
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return resp, body, &retryable{statusError(resp)}
+	}
+	return resp, body, nil
+}
@@ -0,0 +1,111 @@
+package daikin_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+// count returns the number of requests to path.
+func count(s *daikintest.Server, path string) int {
+	n := 0
+	for _, r := range s.Requests() {
+		if strings.SplitN(r, "?", 2)[0] == path {
+			n++
+		}
+	}
+	return n
+}
+
+func TestGateRetries(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+
+	d := newDaikin(s.Address())
+	s.Fail(testPolicy.Retries)
+	if err := d.GetControlInfo(); err != nil {
+		t.Fatalf("read not retried: %v", err)
+	}
+	if n := count(s, "/aircon/get_control_info"); n != testPolicy.Retries+1 {
+		t.Errorf("%d requests, want %d", n, testPolicy.Retries+1)
+	}
+
+	s.Fail(testPolicy.Retries + 1)
+	if err := d.GetSensorInfo(); err == nil {
+		t.Error("read succeeded after all retries failed")
+	}
+}
+
+func TestGateNoRetryOfWrites(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+
+	d := newDaikin(s.Address())
+	if err := d.GetControlInfo(); err != nil {
+		t.Fatal(err)
+	}
+	s.Fail(1)
+	if err := d.SetControl(context.Background(), d.ControlInfo); err == nil {
+		t.Error("write succeeded on busy adapter")
+	}
+	if n := count(s, "/aircon/set_control_info"); n != 1 {
+		t.Errorf("write sent %d times, want once", n)
+	}
+}
+
+func TestGateSerializes(t *testing.T) {
+	fake := daikintest.NewServer()
+	defer fake.Close()
+	var inflight int32
+	var overlap atomic.Bool
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&inflight, 1) > 1 {
+			overlap.Store(true)
+		}
+		time.Sleep(2 * time.Millisecond)
+		fake.ServeHTTP(w, r)
+		atomic.AddInt32(&inflight, -1)
+	}))
+	defer s.Close()
+
+	address := strings.TrimPrefix(s.URL, "http://")
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// separate values for the same unit share the gate
+			if _, err := newDaikin(address).Refresh(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if overlap.Load() {
+		t.Error("concurrent requests to the same unit")
+	}
+}
+
+func TestGateMinGap(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+
+	d := &daikin.Daikin{Address: s.Address(), Policy: &daikin.RequestPolicy{MinGap: 20 * time.Millisecond}}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := d.GetSensorInfo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("3 requests took %v, want at least 2 gaps of 20ms", elapsed)
+	}
+}
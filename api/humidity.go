@@ -1,39 +1,123 @@
 package daikin
 
 import (
+	"encoding/json"
         "fmt"
+	"math"
         "strconv"
 )
 
-// Humidity
+// Humidity in percent. The units report "-" or "--" if there is no
+// humidity sensor and "AUTO" as set humidity in some modes.
 type Humidity struct {
         value int32
         param string
+	// raw holds the non-numeric value as received, it is written
+	// back verbatim.
+	raw string
+	set bool
 }
 
-func (h *Humidity) setUrlValues() string {
-	return h.param + "=" + h.String()
+// The non-numeric set humidity values.
+const (
+	HumidityAuto = "AUTO"
+	HumidityNone = "--"
+)
+
+// encode returns the value as received or, if the humidity is not set,
+// the placeholder "--" of the units.
+func (h *Humidity) encode() string {
+	if len(h.raw) > 0 {
+		return h.raw
+	}
+	if !h.set {
+		return HumidityNone
+	}
+	return strconv.Itoa(int(h.value))
 }
 
 func (h *Humidity) decode(param string, v string) error {
-	if v == "--" || v == "-" {
-		v = "-1"
+	switch v {
+	case HumidityAuto, HumidityNone, "-":
+		*h = Humidity{param: param, raw: v}
+		return nil
 	}
 	val, err := strconv.Atoi(v)
 	if err != nil {
 		return fmt.Errorf("Humidity: error parsing %s=%s: %v", param, v, err)
 	}
-	*h = Humidity{value: int32(val), param: param}
+	*h = Humidity{value: int32(val), param: param, set: true}
 	return nil
 }
 
+// IsSet returns true if the humidity has a numeric value.
+func (h *Humidity) IsSet() bool {
+	return h.set
+}
+
+// IsAuto returns true if the unit reported "AUTO".
+func (h *Humidity) IsAuto() bool {
+	return h.raw == HumidityAuto
+}
+
+// Value returns the humidity, or 0 if it is not set.
+func (h *Humidity) Value() int {
+	return int(h.value)
+}
+
 func (h *Humidity) String() string {
-        if h.value == -1 {
+	if h.IsAuto() {
+		return "Auto"
+	}
+	if !h.IsSet() {
                 return "N/A"
         }
         return strconv.Itoa(int(h.value))
 }
 
+// Float64 returns the humidity, or NaN if it is not set.
 func (h *Humidity) Float64() float64 {
+	if !h.IsSet() {
+		return math.NaN()
+	}
 	return float64(h.value)
 }
+
+// MarshalText returns the humidity, or the non-numeric value as
+// received from the unit.
+func (h Humidity) MarshalText() ([]byte, error) {
+	if !h.set {
+		return []byte(h.raw), nil
+	}
+	return []byte(strconv.Itoa(int(h.value))), nil
+}
+
+// UnmarshalText parses a humidity, "AUTO" or "--".
+func (h *Humidity) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*h = Humidity{param: h.param}
+		return nil
+	}
+	return h.decode(h.param, string(text))
+}
+
+// MarshalJSON encodes the humidity as number, "AUTO" as string and
+// missing values as null.
+func (h Humidity) MarshalJSON() ([]byte, error) {
+	switch {
+	case h.set:
+		return []byte(strconv.Itoa(int(h.value))), nil
+	case h.raw == HumidityAuto:
+		return json.Marshal(h.raw)
+	}
+	return []byte("null"), nil
+}
+
+// UnmarshalJSON accepts a number, a string or null.
+func (h *Humidity) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*h = Humidity{param: h.param, raw: HumidityNone}
+		return nil
+	}
+	return unmarshalJSONText(data, h)
+}
@@ -0,0 +1,312 @@
+package daikin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InventoryEntry is a unit recorded in an Inventory.
+type InventoryEntry struct {
+	// MAC is the MAC address as 12 upper case hex digits.
+	MAC string `yaml:"-" json:"mac"`
+	// Name is the name configured on the unit.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// Address is the address the unit was last seen at.
+	Address string `yaml:"address,omitempty" json:"address,omitempty"`
+	// Model is the model name, if the unit reports it.
+	Model string `yaml:"model,omitempty" json:"model,omitempty"`
+	// Firmware is the firmware version of the adapter.
+	Firmware string `yaml:"firmware,omitempty" json:"firmware,omitempty"`
+	// Alias and Groups are assigned by the user, updates keep them.
+	Alias  string   `yaml:"alias,omitempty" json:"alias,omitempty"`
+	Groups []string `yaml:"groups,omitempty" json:"groups,omitempty"`
+}
+
+func (e *InventoryEntry) String() string {
+	s := fmt.Sprintf("MAC: %s\nName: %s\nAddress: %s", e.MAC, e.Name, e.Address)
+	if len(e.Model) > 0 {
+		s += "\nModel: " + e.Model
+	}
+	if len(e.Firmware) > 0 {
+		s += "\nFirmware Version: " + e.Firmware
+	}
+	if len(e.Alias) > 0 {
+		s += "\nAlias: " + e.Alias
+	}
+	if len(e.Groups) > 0 {
+		s += "\nGroups: " + strings.Join(e.Groups, ", ")
+	}
+	return s
+}
+
+// Inventory is a persistent list of the known units keyed by MAC
+// address. The units get their addresses by DHCP, the inventory maps
+// the MAC address or an alias to the current address. It is updated by
+// Discover and Update, alias and groups are assigned by the user.
+type Inventory struct {
+	// File is the file the inventory is saved to.
+	File string
+
+	mu      sync.Mutex
+	devices map[string]*InventoryEntry
+	changed bool
+}
+
+// inventoryFile is the YAML representation of an Inventory.
+type inventoryFile struct {
+	Devices map[string]*InventoryEntry `yaml:"devices"`
+}
+
+// LoadInventory reads the inventory saved in file. A missing file
+// returns an empty inventory.
+func LoadInventory(file string) (*Inventory, error) {
+	inv := &Inventory{File: file, devices: map[string]*InventoryEntry{}}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return inv, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f inventoryFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	aliases := map[string]string{}
+	for key, e := range f.Devices {
+		mac := NormalizeMAC(key)
+		if len(mac) == 0 {
+			return nil, fmt.Errorf("%s: invalid MAC address %q", file, key)
+		}
+		if e == nil {
+			e = &InventoryEntry{}
+		}
+		e.MAC = mac
+		if len(e.Alias) > 0 {
+			a := strings.ToLower(e.Alias)
+			if other, ok := aliases[a]; ok {
+				return nil, fmt.Errorf("%s: alias %q of %s is already used by %s", file, e.Alias, mac, other)
+			}
+			aliases[a] = mac
+		}
+		inv.devices[mac] = e
+	}
+	return inv, nil
+}
+
+// Save writes the inventory to File, if it changed since it was loaded
+// or saved. The file is replaced atomically.
+func (inv *Inventory) Save() error {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if !inv.changed {
+		return nil
+	}
+
+	var out bytes.Buffer
+	out.WriteString("# Daikin units keyed by MAC address. alias and groups may be edited.\n")
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(inventoryFile{Devices: inv.devices}); err != nil {
+		return err
+	}
+	enc.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(inv.File), filepath.Base(inv.File)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(out.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), inv.File); err != nil {
+		return err
+	}
+	inv.changed = false
+	return nil
+}
+
+// Entries returns copies of the entries sorted by MAC address.
+func (inv *Inventory) Entries() []InventoryEntry {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	entries := make([]InventoryEntry, 0, len(inv.devices))
+	for _, e := range inv.devices {
+		entries = append(entries, e.copy())
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].MAC < entries[j].MAC
+	})
+	return entries
+}
+
+func (e *InventoryEntry) copy() InventoryEntry {
+	c := *e
+	c.Groups = append([]string(nil), e.Groups...)
+	return c
+}
+
+// lookup returns the entry with the MAC address or alias s.
+func (inv *Inventory) lookup(s string) *InventoryEntry {
+	if e, ok := inv.devices[NormalizeMAC(s)]; ok {
+		return e
+	}
+	for _, e := range inv.devices {
+		if len(e.Alias) > 0 && strings.EqualFold(e.Alias, s) {
+			return e
+		}
+	}
+	return nil
+}
+
+// Lookup returns the entry with the MAC address or alias s.
+func (inv *Inventory) Lookup(s string) (InventoryEntry, bool) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if e := inv.lookup(s); e != nil {
+		return e.copy(), true
+	}
+	return InventoryEntry{}, false
+}
+
+// lookupEntry is Lookup for a possibly nil inventory.
+func (inv *Inventory) lookupEntry(s string) (InventoryEntry, bool) {
+	if inv == nil || len(s) == 0 {
+		return InventoryEntry{}, false
+	}
+	return inv.Lookup(s)
+}
+
+// Resolve returns the address the unit with the MAC address or alias s
+// was last seen at. Other values are returned unchanged.
+func (inv *Inventory) Resolve(s string) string {
+	if e, ok := inv.Lookup(s); ok && len(e.Address) > 0 {
+		return e.Address
+	}
+	return s
+}
+
+// Update records the unit at address with the basic info b and the
+// optional model info m. Units without MAC address are ignored. It
+// returns whether the inventory changed.
+func (inv *Inventory) Update(address string, b *BasicInfo, m *ModelInfo) bool {
+	if b == nil {
+		return false
+	}
+	mac := b.MAC()
+	if len(mac) == 0 {
+		return false
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	e, ok := inv.devices[mac]
+	if !ok {
+		e = &InventoryEntry{MAC: mac}
+		inv.devices[mac] = e
+	}
+	old := e.copy()
+	e.Name = b.Name.String()
+	e.Address = address
+	if v := b.Version.String(); len(v) > 0 {
+		e.Firmware = v
+	}
+	if m != nil && m.Model.String() != "NOTSUPPORT" && len(m.Model.String()) > 0 {
+		e.Model = m.Model.String()
+	}
+	changed := !ok || old.Name != e.Name || old.Address != e.Address ||
+		old.Firmware != e.Firmware || old.Model != e.Model
+	if changed {
+		// the address was reassigned by DHCP
+		for _, other := range inv.devices {
+			if other != e && other.Address == address {
+				other.Address = ""
+			}
+		}
+		inv.changed = true
+	}
+	return changed
+}
+
+// updateValues records the unit at address with the basic_info values
+// of a discovery reply.
+func (inv *Inventory) updateValues(address string, values map[string]string) bool {
+	b := &BasicInfo{}
+	if err := b.populate(values); err != nil {
+		return false
+	}
+	return inv.Update(address, b, nil)
+}
+
+// SetAlias assigns alias to the unit with the MAC address or alias
+// unit. An empty alias removes it. Aliases are unique and must not be
+// MAC addresses.
+func (inv *Inventory) SetAlias(unit string, alias string) error {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	e := inv.lookup(unit)
+	if e == nil {
+		return fmt.Errorf("unknown unit %q", unit)
+	}
+	if len(NormalizeMAC(alias)) > 0 || strings.ContainsAny(alias, ",:") {
+		return fmt.Errorf("invalid alias %q", alias)
+	}
+	if other := inv.lookup(alias); len(alias) > 0 && other != nil && other != e {
+		return fmt.Errorf("alias %q is already used by %s", alias, other.MAC)
+	}
+	if e.Alias != alias {
+		e.Alias = alias
+		inv.changed = true
+	}
+	return nil
+}
+
+// SetGroups assigns the groups to the unit with the MAC address or
+// alias unit, replacing the previous groups.
+func (inv *Inventory) SetGroups(unit string, groups []string) error {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	e := inv.lookup(unit)
+	if e == nil {
+		return fmt.Errorf("unknown unit %q", unit)
+	}
+	for _, g := range groups {
+		if len(g) == 0 || strings.ContainsAny(g, ",:") {
+			return fmt.Errorf("invalid group %q", g)
+		}
+	}
+	if strings.Join(e.Groups, ",") != strings.Join(groups, ",") {
+		e.Groups = append([]string(nil), groups...)
+		inv.changed = true
+	}
+	return nil
+}
+
+// Groups returns the MAC addresses of the members per group.
+func (inv *Inventory) Groups() map[string][]string {
+	groups := map[string][]string{}
+	for _, e := range inv.Entries() {
+		for _, g := range e.Groups {
+			groups[g] = append(groups[g], e.MAC)
+		}
+	}
+	return groups
+}
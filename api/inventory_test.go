@@ -0,0 +1,202 @@
+package daikin_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+// basicInfo returns the basic info of the fake adapter s.
+func basicInfo(t *testing.T, s *daikintest.Server) *daikin.BasicInfo {
+	t.Helper()
+	st, err := newDaikin(s.Address()).Refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st.BasicInfo
+}
+
+func TestInventoryUpdate(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+	b := basicInfo(t, s)
+
+	inv, err := daikin.LoadInventory(filepath.Join(t.TempDir(), "inventory.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inv.Update("192.168.1.10", b, nil) {
+		t.Error("new unit not recorded")
+	}
+	if inv.Update("192.168.1.10", b, nil) {
+		t.Error("unchanged unit recorded as change")
+	}
+	e, ok := inv.Lookup("00:11:22:33:44:55")
+	if !ok || e.Name != "Fake" || e.Address != "192.168.1.10" || len(e.Firmware) == 0 {
+		t.Errorf("got %+v", e)
+	}
+
+	// the address was reassigned to another unit
+	s.Set("/common/basic_info", "mac", "001122334466")
+	if !inv.Update("192.168.1.10", basicInfo(t, s), nil) {
+		t.Error("second unit not recorded")
+	}
+	if e, _ := inv.Lookup("001122334455"); len(e.Address) > 0 {
+		t.Errorf("address of the first unit not cleared: %+v", e)
+	}
+	if got := inv.Resolve("001122334466"); got != "192.168.1.10" {
+		t.Errorf("resolved to %q", got)
+	}
+	if got := inv.Resolve("other"); got != "other" {
+		t.Errorf("resolved unknown unit to %q", got)
+	}
+}
+
+func TestInventoryAlias(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+	inv, err := daikin.LoadInventory(filepath.Join(t.TempDir(), "inventory.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv.Update("192.168.1.10", basicInfo(t, s), nil)
+	s.Set("/common/basic_info", "mac", "001122334466")
+	inv.Update("192.168.1.11", basicInfo(t, s), nil)
+
+	if err := inv.SetAlias("001122334455", "living"); err != nil {
+		t.Fatal(err)
+	}
+	if got := inv.Resolve("Living"); got != "192.168.1.10" {
+		t.Errorf("alias resolved to %q", got)
+	}
+	for _, alias := range []string{"LIVING", "00-11-22-33-44-77", "a,b", "a:b"} {
+		if err := inv.SetAlias("001122334466", alias); err == nil {
+			t.Errorf("alias %q accepted", alias)
+		}
+	}
+	if err := inv.SetAlias("unknown", "x"); err == nil {
+		t.Error("alias of unknown unit accepted")
+	}
+	if err := inv.SetGroups("living", []string{"downstairs"}); err != nil {
+		t.Fatal(err)
+	}
+	if g := inv.Groups()["downstairs"]; len(g) != 1 || g[0] != "001122334455" {
+		t.Errorf("groups: %v", g)
+	}
+	// an empty alias removes it
+	if err := inv.SetAlias("living", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := inv.Lookup("living"); ok {
+		t.Error("alias not removed")
+	}
+}
+
+func TestInventorySave(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+	file := filepath.Join(t.TempDir(), "inventory.yaml")
+	inv, err := daikin.LoadInventory(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv.Update("192.168.1.10", basicInfo(t, s), nil)
+	inv.SetAlias("001122334455", "living")
+	inv.SetGroups("living", []string{"downstairs"})
+	if err := inv.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := daikin.LoadInventory(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, want := loaded.Entries(), inv.Entries()
+	if len(got) != 1 || got[0].String() != want[0].String() {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// an unchanged inventory is not written
+	os.Remove(file)
+	if err := loaded.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("unchanged inventory written: %v", err)
+	}
+}
+
+func TestLoadInventoryErrors(t *testing.T) {
+	tests := map[string]string{
+		"devices:\n  nomac: {name: x}\n":                                     "invalid MAC address",
+		"devices:\n  001122334455: {alias: x}\n  001122334466: {alias: X}\n": "already used",
+		"devices: [": "",
+	}
+	for data, want := range tests {
+		file := filepath.Join(t.TempDir(), "inventory.yaml")
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := daikin.LoadInventory(file)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got %v, want %q", data, err, want)
+		}
+	}
+}
+
+func TestSelectInventoryAlias(t *testing.T) {
+	fakes := newFakes(t, 2)
+	inv, err := daikin.LoadInventory(filepath.Join(t.TempDir(), "inventory.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv.Update(fakes[1].Address(), basicInfo(t, fakes[1]), nil)
+	inv.SetAlias("0011223344A1", "bedroom")
+	inv.SetGroups("bedroom", []string{"upstairs"})
+
+	d := newNetwork(t, []string{fakes[0].Address(), fakes[1].Address()}, daikin.InventoryOption(inv))
+	for _, s := range []string{"bedroom", "group:upstairs"} {
+		sel, _ := daikin.ParseSelector(s)
+		devs, err := d.Select(context.Background(), sel)
+		if _, ok := devs[fakes[1].Address()]; err != nil || len(devs) != 1 || !ok {
+			t.Errorf("%s: got %v, %v", s, devs, err)
+		}
+	}
+}
+
+func TestResolveMoved(t *testing.T) {
+	fakes := newFakes(t, 2)
+	inv, err := daikin.LoadInventory(filepath.Join(t.TempDir(), "inventory.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv.Update(fakes[0].Address(), basicInfo(t, fakes[0]), nil)
+	inv.SetAlias("0011223344A0", "living")
+
+	d := newNetwork(t, []string{"living"}, daikin.InventoryOption(inv),
+		daikin.DiscoveryOption(0, 10*time.Millisecond))
+	devs := d.List()
+	if _, ok := devs[fakes[0].Address()]; len(devs) != 1 || !ok || !d.Resolved(fakes[0].Address()) {
+		t.Fatalf("got %v", devs)
+	}
+
+	// the unit got the address of the second fake
+	fakes[1].Set("/common/basic_info", "mac", "0011223344A0")
+	inv.Update(fakes[1].Address(), basicInfo(t, fakes[1]), nil)
+	if err := d.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	devs = d.List()
+	if _, ok := devs[fakes[1].Address()]; len(devs) != 1 || !ok {
+		t.Errorf("got %v", devs)
+	}
+	if d.Resolved(fakes[0].Address()) || !d.Resolved(fakes[1].Address()) {
+		t.Error("previous address still resolved")
+	}
+}
@@ -2,34 +2,97 @@ package daikin
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 )
 
+// KWattHours is an energy consumption. The units report "-" if no
+// value is available.
 type KWattHours struct {
 	value float64
 	param string
+	// raw holds the non-numeric value as received.
+	raw string
+	set bool
 }
 
-func (w *KWattHours) setUrlValues() string {
-	return ""
+func (w *KWattHours) encode() string {
+	if len(w.raw) > 0 {
+		return w.raw
+	}
+	return strconv.FormatFloat(w.value, 'f', -1, 64)
 }
 
 func (w *KWattHours) decode(param string, v string) error {
-	if v == "-" {
-		v = "-1"
+	if v == "-" || v == "--" {
+		*w = KWattHours{param: param, raw: v}
+		return nil
 	}
 	val, err := strconv.ParseFloat(v, 64)
 	if err != nil {
 		return fmt.Errorf("error parsing watt hours=%s: %v", v, err)
 	}
-	*w = KWattHours{value: val, param: param}
+	*w = KWattHours{value: val, param: param, set: true}
 	return nil
 }
 
+// IsSet returns true if the consumption has a numeric value.
+func (w *KWattHours) IsSet() bool {
+	return w.set
+}
+
+// Value returns the consumption, or 0 if it is not set.
+func (w *KWattHours) Value() float64 {
+	return w.value
+}
+
 func (w *KWattHours) String() string {
+	if !w.IsSet() {
+		return "N/A"
+	}
 	return strconv.FormatFloat(w.value, 'f', 1, 64)
 }
 
+// Float64 returns the consumption, or NaN if it is not set.
 func (w *KWattHours) Float64() float64 {
+	if !w.IsSet() {
+		return math.NaN()
+	}
 	return w.value
 }
+
+// MarshalText returns the consumption in kWh, or the non-numeric value
+// as received from the unit.
+func (w KWattHours) MarshalText() ([]byte, error) {
+	if !w.set {
+		return []byte(w.raw), nil
+	}
+	return []byte(strconv.FormatFloat(w.value, 'f', -1, 64)), nil
+}
+
+// UnmarshalText parses a consumption in kWh or "-".
+func (w *KWattHours) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*w = KWattHours{param: w.param}
+		return nil
+	}
+	return w.decode(w.param, string(text))
+}
+
+// MarshalJSON encodes the consumption as number and missing values as
+// null.
+func (w KWattHours) MarshalJSON() ([]byte, error) {
+	if !w.set {
+		return []byte("null"), nil
+	}
+	return []byte(strconv.FormatFloat(w.value, 'f', -1, 64)), nil
+}
+
+// UnmarshalJSON accepts a number, a string or null.
+func (w *KWattHours) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*w = KWattHours{param: w.param, raw: "-"}
+		return nil
+	}
+	return unmarshalJSONText(data, w)
+}
@@ -3,6 +3,7 @@ package daikin
 import (
         "fmt"
         "strconv"
+	"strings"
 )
 
 // Mode is the operating mode of the Daikin unit.
@@ -30,8 +31,23 @@ var modeMap = map[Mode]string{
 	ModeAuto7:      "Auto (7)",
 }
 
-func (m *Mode) setUrlValues() string {
-	return "mode=" + strconv.Itoa(int(*m))
+// modeNames are the symbolic names used for text encoding.
+var modeNames = map[Mode]string{
+	ModeDehumidify: "dehumidify",
+	ModeCool:       "cool",
+	ModeHeat:       "heat",
+	ModeFan:        "fan",
+	ModeAuto:       "auto",
+	ModeAuto1:      "auto1",
+	ModeAuto7:      "auto7",
+}
+
+func (m *Mode) encode() string {
+	return strconv.Itoa(int(*m))
+}
+
+func (m *Mode) decode(param string, s string) error {
+	return m.Decode(s)
 }
 
 func (m *Mode) Decode(s string) error {
@@ -59,3 +75,32 @@ func (m *Mode) Float64() float64 {
 	return float64(*m)
 }
 
+// MarshalText returns the symbolic name like "cool", unknown values as
+// number.
+func (m Mode) MarshalText() ([]byte, error) {
+	if v, ok := modeNames[m]; ok {
+		return []byte(v), nil
+	}
+	return []byte(strconv.Itoa(int(m))), nil
+}
+
+// UnmarshalText accepts the symbolic name, "dry" or the numeric value.
+func (m *Mode) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	if s == "dry" {
+		*m = ModeDehumidify
+		return nil
+	}
+	for k, v := range modeNames {
+		if v == s {
+			*m = k
+			return nil
+		}
+	}
+	return m.Decode(string(text))
+}
+
+// UnmarshalJSON accepts a string or a number.
+func (m *Mode) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, m)
+}
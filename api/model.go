@@ -0,0 +1,99 @@
+package daikin
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ModelInfo represents the model information and the features of the unit.
+type ModelInfo struct {
+	// Model is the model name, most units report "NOTSUPPORT".
+	Model String `daikin:"model"`
+	// Humidity is true if the target humidity can be set.
+	Humidity bool `daikin:"humd"`
+	// FanRate is true if the fan speed can be set.
+	FanRate bool `daikin:"en_frate"`
+	// FanDir is true if the fan louvre can be set.
+	FanDir bool `daikin:"en_fdir"`
+	// FanDirSteps is the supported louvre swing, 1 for vertical
+	// only and 3 for vertical and horizontal.
+	FanDirSteps int `daikin:"s_fdir"`
+	// Zones is the number of zones of ducted systems.
+	Zones int `daikin:"en_zone"`
+	// Setpoints are the target temperature ranges per mode, which
+	// override DefaultSetpoints. They are taken from the limits
+	// reported by AirBase adapters (cool_l, cool_h, heat_l, heat_h)
+	// and may be set by the caller for units with other limits, they
+	// are kept if the model info is fetched again.
+	Setpoints map[Mode]SetpointRange
+	// Extra contains the values without a field.
+	Extra map[string]string `daikin:",extra" json:",omitempty" yaml:",omitempty"`
+
+	// fanRateKnown is set if the unit reported en_frate
+	fanRateKnown bool
+	// fanDirKnown is set if the unit reported en_fdir
+	fanDirKnown bool
+}
+
+// setpointKeys are the keys of the lower and upper limits per mode.
+var setpointKeys = map[Mode][2]string{
+	ModeCool: {"cool_l", "cool_h"},
+	ModeHeat: {"heat_l", "heat_h"},
+}
+
+// ret=OK,model=NOTSUPPORT,type=N,pv=2,cpv=2,cpv_minor=00,mid=NA,humd=0,s_humd=0,acled=0,land=0,elec=1,temp=1,temp_rng=0,m_dtct=1,ac_dst=--,disp_dry=0,dmnd=0,en_scdltmr=1,en_frate=1,en_fdir=1,s_fdir=3,en_rtemp_a=0,en_spmode=0,en_ipw_sep=0,en_mompow=0
+func (m *ModelInfo) populate(values map[string]string) error {
+	if err := decodeValues(m, values); err != nil {
+		return fmt.Errorf("ModelInfo: %v", err)
+	}
+	_, m.fanRateKnown = values["en_frate"]
+	_, m.fanDirKnown = values["en_fdir"]
+	for mode, keys := range setpointKeys {
+		min, err1 := strconv.ParseFloat(values[keys[0]], 64)
+		max, err2 := strconv.ParseFloat(values[keys[1]], 64)
+		if err1 != nil || err2 != nil || min >= max {
+			continue
+		}
+		if m.Setpoints == nil {
+			m.Setpoints = map[Mode]SetpointRange{}
+		}
+		r := DefaultSetpoints[mode]
+		r.Min, r.Max = min, max
+		m.Setpoints[mode] = r
+	}
+	return nil
+}
+
+// FanDirs returns the louvre settings supported by the unit: only
+// FanDirStopped if the louvre cannot be set, vertical swing if
+// FanDirSteps is 1 and all settings for 3D airflow units or if the unit
+// did not report en_fdir.
+func (m *ModelInfo) FanDirs() []FanDir {
+	if !m.fanDirSupported() {
+		return []FanDir{FanDirStopped}
+	}
+	switch m.FanDirSteps {
+	case 1:
+		return []FanDir{FanDirStopped, FanDirVertical}
+	case 2:
+		return []FanDir{FanDirStopped, FanDirHorizontal}
+	}
+	return []FanDir{FanDirStopped, FanDirVertical, FanDirHorizontal, FanDirBoth}
+}
+
+// fanRateSupported returns false only if the unit reported that the
+// fan speed cannot be set.
+func (m *ModelInfo) fanRateSupported() bool {
+	return m.FanRate || !m.fanRateKnown
+}
+
+// fanDirSupported returns false only if the unit reported that the
+// louvre cannot be set.
+func (m *ModelInfo) fanDirSupported() bool {
+	return m.FanDir || !m.fanDirKnown
+}
+
+func (m *ModelInfo) String() string {
+	return fmt.Sprintf("Model: %s\nHumidity control: %t\nFan speed control: %t\nFan louvre control: %t",
+		m.Model.String(), m.Humidity, m.FanRate, m.FanDir)
+}
@@ -1,6 +1,7 @@
 package daikin
 
 import (
+	"math"
         "net/url"
 )
 
@@ -14,8 +15,13 @@ func (n *Name) String() string {
 	return n.value
 }
 
-func (n *Name) setUrlValues() string {
-	return n.param + "=" + url.PathEscape(n.String())
+func (n *Name) encode() string {
+	return url.PathEscape(n.String())
+}
+
+// Float64 returns NaN, names are not numeric.
+func (n *Name) Float64() float64 {
+	return math.NaN()
 }
 
 func (n *Name) decode(param string, s string) error {
@@ -27,3 +33,13 @@ func (n *Name) decode(param string, s string) error {
 	return nil
 }
 
+// MarshalText returns the value.
+func (n Name) MarshalText() ([]byte, error) {
+	return []byte(n.value), nil
+}
+
+// UnmarshalText sets the value.
+func (n *Name) UnmarshalText(text []byte) error {
+	n.value = string(text)
+	return nil
+}
@@ -1,8 +1,11 @@
 package daikin
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"slices"
+	"sync"
 	"time"
 
 	log "github.com/thkukuk/mqtt-exporter/pkg/logger"
@@ -14,6 +17,9 @@ const (
 	udpQueryPayload = "DAIKIN_UDP/common/basic_info"
 )
 
+// DefaultConcurrency is the default of DaikinNetwork.Concurrency.
+const DefaultConcurrency = 4
+
 // Option is an option type to pass to NewNetwork.
 type Option func(*DaikinNetwork)
 
@@ -24,15 +30,117 @@ func InterfaceOption(i string) func(*DaikinNetwork) {
 	}
 }
 
-// AddressOption specifies a specific address to query.
+// AddressOption specifies a specific address to query, it may be
+// passed several times. The address may also be the MAC address of a
+// unit or, with InventoryOption, its alias, which Discover resolves.
+// Unless DiscoveryOption is given, only these units are used.
 func AddressOption(addr string) func(*DaikinNetwork) {
 	return func(d *DaikinNetwork) {
 		if addr != "" {
-			d.Devices = map[string]*Daikin{
-				addr: &Daikin{Address: addr},
-			}
-			d.PollCount = 0
+			d.addresses = append(d.addresses, addr)
+		}
+	}
+}
+
+// DiscoveryOption configures the discovery of units. Discover polls
+// count times, waiting interval for replies, and adds all units found
+// to the units of AddressOption. With count 0, MAC addresses are still
+// resolved, but only the units of AddressOption are used.
+func DiscoveryOption(count int, interval time.Duration) func(*DaikinNetwork) {
+	return func(d *DaikinNetwork) {
+		d.PollCount = count
+		if interval > 0 {
+			d.PollInterval = interval
+		}
+		d.discovery = true
+	}
+}
+
+// DeviceSettings are settings of a single unit, which override the
+// settings of the network.
+type DeviceSettings struct {
+	// Alias is an additional name for selectors.
+	Alias string
+	// Groups are groups for selectors the unit is member of.
+	Groups []string
+	// Labels are user-defined labels, e.g. for metrics.
+	Labels map[string]string
+	// Credentials override CredentialsOption.
+	Credentials *Credentials
+	// Policy overrides PolicyOption.
+	Policy *RequestPolicy
+	// Cache overrides the TTLs of CacheOption, it is ignored without.
+	Cache *CacheTTL
+}
+
+// DeviceOption configures the settings of the unit with the address or
+// MAC address key.
+func DeviceOption(key string, s DeviceSettings) func(*DaikinNetwork) {
+	return func(d *DaikinNetwork) {
+		if mac := NormalizeMAC(key); len(mac) > 0 {
+			key = mac
 		}
+		d.settings[key] = s
+	}
+}
+
+// DeviceFactory creates the Device for address. values are the
+// basic_info values of the discovery reply, or nil if the device was
+// configured with AddressOption.
+type DeviceFactory func(address string, values map[string]string) Device
+
+// FactoryOption configures the factory used to create the devices.
+func FactoryOption(f DeviceFactory) func(*DaikinNetwork) {
+	return func(d *DaikinNetwork) {
+		d.factory = f
+	}
+}
+
+// CredentialsOption configures the access credentials of the devices,
+// keyed by address or MAC address.
+func CredentialsOption(c map[string]Credentials) func(*DaikinNetwork) {
+	return func(d *DaikinNetwork) {
+		d.credentials = c
+	}
+}
+
+// PolicyOption configures the request policy of the devices.
+func PolicyOption(p RequestPolicy) func(*DaikinNetwork) {
+	return func(d *DaikinNetwork) {
+		d.policy = &p
+	}
+}
+
+// CacheOption enables caching of the device state with the given TTLs.
+func CacheOption(ttl CacheTTL) func(*DaikinNetwork) {
+	return func(d *DaikinNetwork) {
+		d.cache = &ttl
+	}
+}
+
+// GroupsOption configures named groups of devices for selectors, see
+// Selector. The members are addresses, MAC addresses or names.
+func GroupsOption(g map[string][]string) func(*DaikinNetwork) {
+	return func(d *DaikinNetwork) {
+		d.groups = g
+	}
+}
+
+// ConcurrencyOption limits the number of devices Select and Apply
+// contact at the same time.
+func ConcurrencyOption(n int) func(*DaikinNetwork) {
+	return func(d *DaikinNetwork) {
+		d.Concurrency = n
+	}
+}
+
+// InventoryOption configures the inventory of the known units. Discover
+// records the units found in it. A MAC address or alias passed with
+// AddressOption is resolved by Discover to the current address of the
+// unit, or else to the address it was last seen at.
+func InventoryOption(inv *Inventory) func(*DaikinNetwork) {
+	return func(d *DaikinNetwork) {
+		d.inventory = inv
 	}
 }
 
@@ -48,11 +156,26 @@ func NewNetwork(o ...Option) (*DaikinNetwork, error) {
 	dn := &DaikinNetwork{
 		PollInterval: time.Second,
 		PollCount:    1,
-		Devices:      map[string]*Daikin{},
+		Concurrency:  DefaultConcurrency,
+		Devices:      map[string]Device{},
+		discovered:   map[string]map[string]string{},
+		resolved:     map[string]string{},
+		settings:     map[string]DeviceSettings{},
 	}
 	for _, opt := range o {
 		opt(dn)
 	}
+	for _, addr := range dn.addresses {
+		if mac := dn.macOf(addr); len(mac) > 0 {
+			// the address is resolved by Discover
+			dn.resolve = append(dn.resolve, mac)
+			continue
+		}
+		dn.Devices[addr] = dn.newDevice(addr, "", nil)
+	}
+	if len(dn.addresses) > 0 && !dn.discovery {
+		dn.PollCount = 0
+	}
 	return dn, nil
 }
 
@@ -65,15 +188,115 @@ type DaikinNetwork struct {
 	PollInterval time.Duration
 	// PollCount is the number of times to poll for Daikin devices.
 	PollCount int
+	// Concurrency is the maximum number of devices Select and Apply
+	// contact at the same time.
+	Concurrency int
 
 	// Devices are the Daikin devices found on the DaikinNetwork.
-	Devices map[string]*Daikin
+	// Discover adds devices, use List while it may run.
+	Devices map[string]Device
 
+	// mu protects Devices and discovered
+	mu         sync.RWMutex
 	broadcasts []net.IP
+	// discovered are the basic_info values of the discovery replies
+	discovered map[string]map[string]string
+
+	addresses   []string
+	discovery   bool
+	settings    map[string]DeviceSettings
+	credentials map[string]Credentials
+	factory     DeviceFactory
+	policy      *RequestPolicy
+	cache       *CacheTTL
+	groups      map[string][]string
+	inventory   *Inventory
+	// resolve are the MAC addresses of the units Discover has to find
+	resolve []string
+	// resolved are the current addresses of the units to resolve by
+	// MAC address
+	resolved map[string]string
 
 	verbose bool
 }
 
+// macOf returns the MAC address of the unit with the MAC address or
+// alias s, "" if s is neither.
+func (d *DaikinNetwork) macOf(s string) string {
+	if mac := NormalizeMAC(s); len(mac) > 0 {
+		return mac
+	}
+	if d.inventory != nil && len(s) > 0 {
+		if e, ok := d.inventory.Lookup(s); ok {
+			return e.MAC
+		}
+	}
+	return ""
+}
+
+// newDevice creates the Device for address with the configured factory.
+// By default, a Daikin is created, which detects the protocol of the
+// unit with the first request. mac is the MAC address of the unit, if
+// known without discovery reply.
+func (d *DaikinNetwork) newDevice(address string, mac string, values map[string]string) Device {
+	if d.factory != nil {
+		return d.factory(address, values)
+	}
+	dev := &Daikin{Address: address, Policy: d.policy, Cache: d.cache}
+	if values != nil {
+		mac = NormalizeMAC(values["mac"])
+	}
+	if c, ok := d.credentials[address]; ok {
+		dev.Credentials = c
+	}
+	for k, c := range d.credentials {
+		if len(mac) > 0 && NormalizeMAC(k) == mac {
+			dev.Credentials = c
+		}
+	}
+	if s, ok := d.settingsOf(address, mac); ok {
+		if s.Credentials != nil {
+			dev.Credentials = *s.Credentials
+		}
+		if s.Policy != nil {
+			dev.Policy = s.Policy
+		}
+		if s.Cache != nil && d.cache != nil {
+			dev.Cache = s.Cache
+		}
+	}
+	return dev
+}
+
+// settingsOf returns the settings of DeviceOption for the unit with
+// address or MAC address mac.
+func (d *DaikinNetwork) settingsOf(address string, mac string) (DeviceSettings, bool) {
+	if s, ok := d.settings[address]; ok {
+		return s, true
+	}
+	if len(mac) > 0 {
+		s, ok := d.settings[mac]
+		return s, ok
+	}
+	return DeviceSettings{}, false
+}
+
+// Settings returns the settings of DeviceOption for the unit at
+// target. Units configured by MAC address are identified by the
+// discovery reply or the last basic info of the device.
+func (d *DaikinNetwork) Settings(target string) (DeviceSettings, bool) {
+	d.mu.RLock()
+	mac := NormalizeMAC(d.discovered[target]["mac"])
+	dev, ok := d.Devices[target]
+	d.mu.RUnlock()
+	if ok && len(mac) == 0 {
+		if s := dev.Snapshot(); s.BasicInfo != nil {
+			mac = s.BasicInfo.MAC()
+		}
+	}
+	return d.settingsOf(target, mac)
+}
+
 // getBroadcastAddresses fetches and populates the interface broadcast addresses.
 func (d *DaikinNetwork) getBroadcastAddresses() error {
 	d.broadcasts = []net.IP{}
@@ -125,8 +348,17 @@ func (d *DaikinNetwork) getBroadcastAddresses() error {
 // Discover runs a UDP polling cycle for Daikin devices.
 // Sends UDP packet to broadcast address, dst port 30050 with payload:
 // DAIKIN_UDP/common/basic_info
+//
+// Units configured by MAC address are resolved to their current
+// address. If some of them are not found, an error is returned, but
+// the other units are kept. Discover may be called again to follow
+// units to a new address.
 func (d *DaikinNetwork) Discover() error {
-	if d.PollCount < 1 {
+	polls := d.PollCount
+	if polls < 1 && len(d.resolve) > 0 {
+		polls = 1
+	}
+	if polls < 1 {
 		return nil
 	}
 	if err := d.getBroadcastAddresses(); err != nil {
@@ -140,12 +372,15 @@ func (d *DaikinNetwork) Discover() error {
 	}
 	defer conn.Close()
 
+	// replies are the basic_info values of this cycle by address
+	replies := map[string]map[string]string{}
+
 	// A poller sends to broadcast and awaits replies.
 	poller := func(bCast string, done chan bool) {
 		if d.verbose {
 			log.Debugf("Start polling to: %s", bCast)
 		}
-		for i := 0; i < d.PollCount; i++ {
+		for i := 0; i < polls; i++ {
 			// Send broadcast packet.
 			rAddr := &net.UDPAddr{IP: net.ParseIP(bCast), Port: 30050}
 			if _, err := conn.WriteToUDP([]byte(udpQueryPayload), rAddr); err != nil {
@@ -169,10 +404,18 @@ func (d *DaikinNetwork) Discover() error {
 				}
 
 				ip := rAddr.IP.String()
+				// The reply contains the basic_info values
+				vals, err := ParseValues(rBuf[:n])
+				if err != nil {
+					vals = map[string]string{}
+				}
+				d.mu.Lock()
 				if _, ok := d.Devices[ip]; !ok {
-					dev := &Daikin{Address: ip}
-					d.Devices[ip] = dev
+					d.Devices[ip] = d.newDevice(ip, "", vals)
 				}
+				d.discovered[ip] = vals
+				replies[ip] = vals
+				d.mu.Unlock()
 			}
 		}
 		close(done)
@@ -189,5 +432,109 @@ func (d *DaikinNetwork) Discover() error {
 		_, _ = <-ch
 	}
 
-	return nil
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.inventory != nil {
+		for ip, vals := range replies {
+			d.inventory.updateValues(ip, vals)
+		}
+	}
+	return d.resolveAddresses(replies)
+}
+
+// List returns a copy of Devices. Unlike Devices, it may be used while
+// Discover runs.
+func (d *DaikinNetwork) List() map[string]Device {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	devs := make(map[string]Device, len(d.Devices))
+	for t, dev := range d.Devices {
+		devs[t] = dev
+	}
+	return devs
+}
+
+// Resolved returns true if target is the address of a unit configured
+// by MAC address or alias, which Discover resolves again.
+func (d *DaikinNetwork) Resolved(target string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, addr := range d.resolved {
+		if addr == target {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveAddresses keeps the units which replied with the MAC
+// addresses to resolve and, if discovery is enabled, all others. Units
+// which did not reply are used at the address they were last seen at.
+// A unit found at a new address replaces the device at the previous
+// one. The caller holds mu.
+func (d *DaikinNetwork) resolveAddresses(replies map[string]map[string]string) error {
+	found := map[string]string{}
+	for ip, vals := range replies {
+		mac := NormalizeMAC(vals["mac"])
+		if slices.Contains(d.resolve, mac) {
+			found[mac] = ip
+		} else if d.PollCount < 1 && !slices.Contains(d.addresses, ip) {
+			delete(d.Devices, ip)
+		}
+	}
+
+	var errs []error
+	moved := map[string]string{}
+	for _, mac := range d.resolve {
+		addr, ok := found[mac]
+		if !ok && d.inventory != nil {
+			if e, ok := d.inventory.Lookup(mac); ok {
+				addr = e.Address
+			}
+		}
+		if len(addr) == 0 {
+			addr = d.resolved[mac]
+		}
+		if len(addr) == 0 {
+			errs = append(errs, fmt.Errorf("unit %s not found", mac))
+			continue
+		}
+		if !ok && d.verbose {
+			log.Debugf("%s not discovered, using last address %s", mac, addr)
+		}
+		if addr != d.resolved[mac] {
+			moved[mac] = addr
+		}
+	}
+
+	// remove the previous addresses first, another unit may have got
+	// one of them
+	for mac := range moved {
+		if old := d.resolved[mac]; len(old) > 0 && !slices.Contains(d.addresses, old) {
+			if d.verbose {
+				log.Debugf("%s moved from %s to %s", mac, old, moved[mac])
+			}
+			delete(d.Devices, old)
+			if _, ok := replies[old]; !ok {
+				delete(d.discovered, old)
+			}
+		}
+	}
+	for mac, addr := range moved {
+		d.Devices[addr] = d.newDevice(addr, mac, replies[addr])
+		d.resolved[mac] = addr
+	}
+	for mac, addr := range d.resolved {
+		if _, ok := d.Devices[addr]; !ok {
+			d.Devices[addr] = d.newDevice(addr, mac, replies[addr])
+		}
+	}
+	if d.PollCount > 0 {
+		for ip, vals := range replies {
+			if _, ok := d.Devices[ip]; !ok {
+				d.Devices[ip] = d.newDevice(ip, "", vals)
+			}
+		}
+	}
+	return errors.Join(errs...)
 }
@@ -3,6 +3,7 @@ package daikin
 import (
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 // Power represents the power status of the unit (off/on).
@@ -19,18 +20,18 @@ var powerMap = map[Power]string{
 	PowerOn:  "On",
 }
 
-func (p *Power) setUrlValues() string {
-	return "pow=" + strconv.Itoa(int(*p))
+func (p *Power) encode() string {
+	return strconv.Itoa(int(*p))
 }
 
-func (p *Power) decode(s string) error {
+func (p *Power) decode(param string, s string) error {
 	switch s {
 	case "0":
 		*p = Power(PowerOff)
 	case "1":
 		*p = Power(PowerOn)
 	default:
-		return fmt.Errorf("unknown pwr value: %s", s)
+		return fmt.Errorf("unknown %s value: %s", param, s)
 	}
 	return nil
 }
@@ -47,3 +48,31 @@ func (p *Power) Float64() float64 {
 	return float64(*p)
 }
 
+// MarshalText returns "off" or "on", unknown values as number.
+func (p Power) MarshalText() ([]byte, error) {
+	switch p {
+	case PowerOff:
+		return []byte("off"), nil
+	case PowerOn:
+		return []byte("on"), nil
+	}
+	return []byte(strconv.Itoa(int(p))), nil
+}
+
+// UnmarshalText accepts "off", "on", "0" and "1".
+func (p *Power) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "off":
+		*p = PowerOff
+		return nil
+	case "on":
+		*p = PowerOn
+		return nil
+	}
+	return p.decode("pow", string(text))
+}
+
+// UnmarshalJSON accepts a string or a number.
+func (p *Power) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, p)
+}
@@ -0,0 +1,80 @@
+package daikin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidParameter is returned if the unit rejects a request with
+// ret=PARAM NG.
+var ErrInvalidParameter = errors.New("invalid parameter")
+
+// ResponseError is returned if the unit answers a request with a ret
+// value other than OK.
+type ResponseError struct {
+	// Path is the requested endpoint.
+	Path string
+	// Ret is the ret value of the reply, e.g. "PARAM NG".
+	Ret string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s: device returned error ret=%s", e.Path, e.Ret)
+}
+
+// Unwrap returns ErrInvalidParameter for ret=PARAM NG.
+func (e *ResponseError) Unwrap() error {
+	if e.Ret == returnBad {
+		return ErrInvalidParameter
+	}
+	return nil
+}
+
+// checkRet returns a ResponseError if values has a ret other than OK.
+func checkRet(path string, values map[string]string) error {
+	if v := values["ret"]; v != returnOk {
+		return &ResponseError{Path: path, Ret: v}
+	}
+	return nil
+}
+
+// GetRaw queries the endpoint path, e.g. "/aircon/get_timer", and
+// returns all key/value pairs of the reply, still percent-encoded. The
+// path is sent as is, without translation for the protocol of the unit.
+// If the unit does not know the endpoint, ErrNotSupported is returned.
+// A ret other than OK is returned as ResponseError together with the
+// values.
+func (d *Daikin) GetRaw(ctx context.Context, path string) (map[string]string, error) {
+	vals, err := d.get(ctx, path, "")
+	if err != nil {
+		return nil, err
+	}
+	return vals, checkRet(path, vals)
+}
+
+// SetRaw sends params to the endpoint path, e.g.
+// "/aircon/set_control_info", and returns the key/value pairs of the
+// reply. The values are sent verbatim, so they have to be
+// percent-encoded like the values returned by GetRaw. The request is
+// not retried and all cached state is invalidated.
+func (d *Daikin) SetRaw(ctx context.Context, path string, params map[string]string) (map[string]string, error) {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	query := make([]string, len(keys))
+	for i, k := range keys {
+		query[i] = k + "=" + params[k]
+	}
+
+	d.Invalidate()
+	vals, err := d.request(ctx, false, path, strings.Join(query, "&"))
+	if err != nil {
+		return nil, err
+	}
+	return vals, checkRet(path, vals)
+}
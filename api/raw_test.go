@@ -0,0 +1,100 @@
+package daikin_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+func TestGetRaw(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+	s.Set("/aircon/get_timer", "name", "%4c%69%76%69%6e%67")
+
+	d := newDaikin(s.Address())
+	ctx := context.Background()
+	vals, err := d.GetRaw(ctx, "/aircon/get_timer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vals["ret"] != "OK" || vals["name"] != "%4c%69%76%69%6e%67" {
+		t.Errorf("got %v", vals)
+	}
+
+	if _, err := d.GetRaw(ctx, "/aircon/get_unknown"); !errors.Is(err, daikin.ErrNotSupported) {
+		t.Errorf("unknown endpoint: got %v, want ErrNotSupported", err)
+	}
+}
+
+func TestGetRawRejected(t *testing.T) {
+	f := &daikin.Fixture{Exchanges: []daikin.Exchange{{Method: "GET", Path: "/aircon/get_timer",
+		Status: 200, Body: "ret=PARAM NG,msg=busy"}}}
+	r := daikintest.NewReplayServer(f)
+	defer r.Close()
+
+	vals, err := newDaikin(r.Address()).GetRaw(context.Background(), "/aircon/get_timer")
+	var re *daikin.ResponseError
+	if !errors.As(err, &re) || re.Ret != "PARAM NG" || re.Path != "/aircon/get_timer" {
+		t.Fatalf("got %v, want ResponseError", err)
+	}
+	if !errors.Is(err, daikin.ErrInvalidParameter) {
+		t.Errorf("%v is not ErrInvalidParameter", err)
+	}
+	if vals["msg"] != "busy" {
+		t.Errorf("values %v not returned", vals)
+	}
+}
+
+func TestSetRaw(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+
+	d := newDaikin(s.Address())
+	d.Cache = &daikin.CacheTTL{BasicInfo: time.Hour, ControlInfo: time.Hour}
+	ctx := context.Background()
+	if _, err := d.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	vals, err := d.SetRaw(ctx, "/aircon/set_control_info", map[string]string{"pow": "1", "mode": "4"})
+	if err != nil || vals["ret"] != "OK" {
+		t.Fatalf("got %v, %v", vals, err)
+	}
+	if got := s.Get("/aircon/get_control_info", "pow"); got != "1" {
+		t.Errorf("pow is %q, want 1", got)
+	}
+	// the cached control info is discarded
+	if _, ok := d.CacheAge(daikin.SectionControlInfo); ok {
+		t.Error("control info still cached")
+	}
+	st, err := d.Refresh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.ControlInfo.Power != daikin.PowerOn || st.ControlInfo.Mode != daikin.ModeHeat {
+		t.Errorf("got %s", st.ControlInfo)
+	}
+
+	// a malformed query is rejected
+	_, err = d.SetRaw(ctx, "/aircon/set_control_info", map[string]string{"x": "1&y"})
+	if !errors.Is(err, daikin.ErrInvalidParameter) {
+		t.Errorf("got %v, want ErrInvalidParameter", err)
+	}
+
+	// set requests are not retried
+	s.Fail(1)
+	n := count(s, "/aircon/set_control_info")
+	if _, err := d.SetRaw(ctx, "/aircon/set_control_info", map[string]string{"pow": "0"}); err == nil {
+		t.Error("no error for failed request")
+	}
+	if got := count(s, "/aircon/set_control_info") - n; got != 1 {
+		t.Errorf("%d requests, want 1", got)
+	}
+	if got := s.Get("/aircon/get_control_info", "pow"); got != "1" {
+		t.Errorf("pow is %q, want 1", got)
+	}
+}
@@ -0,0 +1,360 @@
+package daikin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Exchange is a recorded request to a unit and its response.
+type Exchange struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	// RequestBody is the body of dsiot requests.
+	RequestBody string `json:"request_body,omitempty"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+// Fixture contains the recorded exchanges with one unit.
+type Fixture struct {
+	// Comment describes the unit, e.g. the adapter model.
+	Comment   string     `json:"comment,omitempty"`
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+// LoadFixture reads a fixture written by Save.
+func LoadFixture(file string) (*Fixture, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	f := &Fixture{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return f, nil
+}
+
+// Save writes the fixture as indented JSON to file.
+func (f *Fixture) Save(file string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// redactedKeys are the keys, query parameters and dsiot resource names
+// with values identifying the unit or the network, which are replaced
+// in recorded exchanges.
+var redactedKeys = map[string]string{
+	"mac":      "000000000000",
+	"ssid":     redacted,
+	"ssid1":    redacted,
+	"key":      redacted,
+	"id":       redacted,
+	"pw":       redacted,
+	"lpw":      redacted,
+	"password": redacted,
+	"uuid":     redacted,
+}
+
+const redacted = "REDACTED"
+
+// redactValues replaces the sensitive values of a query string or
+// fields with separator sep.
+func redactValues(s string, sep string) string {
+	if len(s) == 0 {
+		return s
+	}
+	fields := strings.Split(s, sep)
+	for i, f := range fields {
+		k, v, ok := strings.Cut(f, "=")
+		if r, found := redactedKeys[strings.ToLower(k)]; ok && found && len(v) > 0 {
+			fields[i] = k + "=" + r
+		}
+	}
+	return strings.Join(fields, sep)
+}
+
+// redactJSON replaces the sensitive values of a dsiot body. Bodies which
+// are no valid JSON are returned unchanged.
+func redactJSON(s string) string {
+	var v interface{}
+	d := json.NewDecoder(strings.NewReader(s))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return s
+	}
+	redactTree(v)
+	out, err := json.Marshal(v)
+	if err != nil {
+		return s
+	}
+	return string(out)
+}
+
+func redactTree(v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if pn, ok := t["pn"].(string); ok {
+			if r, found := redactedKeys[strings.ToLower(pn)]; found {
+				if _, ok := t["pv"]; ok {
+					t["pv"] = r
+				}
+			}
+		}
+		for k, c := range t {
+			if r, found := redactedKeys[strings.ToLower(k)]; found {
+				if _, ok := c.(string); ok {
+					t[k] = r
+					continue
+				}
+			}
+			redactTree(c)
+		}
+	case []interface{}:
+		for _, c := range t {
+			redactTree(c)
+		}
+	}
+}
+
+// redactReply replaces the sensitive values of a key=value reply. The
+// fields are found with the Tokenizer, so that fields on separate lines
+// and quoted values are redacted, the rest of the reply is kept as is.
+// Replies the tokenizer rejects are split at every separator instead.
+func redactReply(body string) string {
+	var out strings.Builder
+	t := NewTokenizer([]byte(body), ParseOptions{Lenient: true})
+	last := 0
+	for {
+		f, err := t.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return redactValues(strings.NewReplacer("\r\n", ",", "\r", ",", "\n", ",").Replace(body), ",")
+		}
+		r, found := redactedKeys[strings.ToLower(f.Key)]
+		if !found || len(f.Value) == 0 {
+			continue
+		}
+		end := t.pos
+		switch {
+		case strings.HasSuffix(body[:end], "\r\n"):
+			end -= 2
+		case end > f.Offset && isSeparator(body[end-1]):
+			end--
+		}
+		out.WriteString(body[last:f.Offset])
+		out.WriteString(f.Key + "=" + r)
+		last = end
+	}
+	out.WriteString(body[last:])
+	return out.String()
+}
+
+// redactBody replaces the sensitive values of a request or response body.
+func redactBody(body string) string {
+	if strings.HasPrefix(strings.TrimSpace(body), "{") {
+		return redactJSON(body)
+	}
+	return redactReply(body)
+}
+
+// Recorder is an http.RoundTripper, which records all exchanges with
+// the units. MAC addresses, SSIDs, keys and passwords are redacted, so
+// that the fixture can be attached to bug reports. It is plugged into a
+// Daikin with:
+//
+//	rec := &Recorder{}
+//	d.Client = rec.Client()
+type Recorder struct {
+	// Transport sends the requests. If nil, the transport of the
+	// default client is used, which accepts the self-signed
+	// certificates of the adapters.
+	Transport http.RoundTripper
+
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+// Client returns an HTTP client recording with r.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Timeout: defaultClient.Timeout, Transport: r}
+}
+
+func (r *Recorder) transport() http.RoundTripper {
+	if r.Transport != nil {
+		return r.Transport
+	}
+	return defaultClient.Transport
+}
+
+// RoundTrip sends the request and records the exchange. Failed requests
+// are not recorded.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := r.transport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fixture.Exchanges = append(r.fixture.Exchanges, Exchange{
+		Method:      req.Method,
+		Path:        req.URL.Path,
+		Query:       redactValues(req.URL.RawQuery, "&"),
+		RequestBody: redactBody(string(reqBody)),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        redactBody(string(body)),
+	})
+	return resp, nil
+}
+
+// Fixture returns a copy of the exchanges recorded so far.
+func (r *Recorder) Fixture() *Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := &Fixture{Comment: r.fixture.Comment}
+	f.Exchanges = append(f.Exchanges, r.fixture.Exchanges...)
+	return f
+}
+
+// Replay serves the exchanges of a fixture instead of a unit. It is an
+// http.RoundTripper for Daikin.Client and an http.Handler for a test
+// server. A request is answered with the recorded exchange with the
+// same method, path, query and body, or else with the same method and
+// path. Repeated requests get the recorded responses in order, the last
+// one is repeated. Unknown requests are answered with 404.
+type Replay struct {
+	mu       sync.Mutex
+	fixture  *Fixture
+	consumed map[string]int
+}
+
+// NewReplay returns a Replay serving f.
+func NewReplay(f *Fixture) *Replay {
+	return &Replay{fixture: f, consumed: map[string]int{}}
+}
+
+// match returns the exchange for the request, nil if there is none.
+func (r *Replay) match(method string, path string, query string, body string) *Exchange {
+	query = redactValues(query, "&")
+	body = redactBody(body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	exact := func(e *Exchange) bool {
+		return e.Method == method && e.Path == path && e.Query == query && e.RequestBody == body
+	}
+	byPath := func(e *Exchange) bool {
+		return e.Method == method && e.Path == path
+	}
+	for i, m := range []func(*Exchange) bool{exact, byPath} {
+		var found []*Exchange
+		for j := range r.fixture.Exchanges {
+			if m(&r.fixture.Exchanges[j]) {
+				found = append(found, &r.fixture.Exchanges[j])
+			}
+		}
+		if len(found) == 0 {
+			continue
+		}
+		key := fmt.Sprintf("%d %s %s?%s %s", i, method, path, query, body)
+		if i == 1 {
+			key = fmt.Sprintf("%d %s %s", i, method, path)
+		}
+		n := r.consumed[key]
+		r.consumed[key] = n + 1
+		if n >= len(found) {
+			n = len(found) - 1
+		}
+		return found[n]
+	}
+	return nil
+}
+
+func readBody(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+	defer req.Body.Close()
+	b, err := io.ReadAll(req.Body)
+	return string(b), err
+}
+
+// RoundTrip answers the request from the fixture.
+func (r *Replay) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp := &http.Response{
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Request:    req,
+	}
+	e := r.match(req.Method, req.URL.Path, req.URL.RawQuery, body)
+	if e == nil {
+		resp.StatusCode = http.StatusNotFound
+		resp.Status = "404 Not Found"
+		resp.Body = io.NopCloser(strings.NewReader(""))
+		return resp, nil
+	}
+	resp.StatusCode = e.Status
+	resp.Status = fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status))
+	if len(e.ContentType) > 0 {
+		resp.Header.Set("Content-Type", e.ContentType)
+	}
+	resp.Body = io.NopCloser(strings.NewReader(e.Body))
+	resp.ContentLength = int64(len(e.Body))
+	return resp, nil
+}
+
+// ServeHTTP answers the request from the fixture.
+func (r *Replay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := readBody(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	e := r.match(req.Method, req.URL.Path, req.URL.RawQuery, body)
+	if e == nil {
+		http.NotFound(w, req)
+		return
+	}
+	if len(e.ContentType) > 0 {
+		w.Header().Set("Content-Type", e.ContentType)
+	}
+	w.WriteHeader(e.Status)
+	io.WriteString(w, e.Body)
+}
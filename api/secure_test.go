@@ -0,0 +1,121 @@
+package daikin_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/thkukuk/daikin-gomod/api"
+)
+
+// secureServer is a fake adapter only accepting HTTPS requests. It
+// replies ret to every request and records the requests.
+type secureServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func newSecureServer(t *testing.T, ret string) *secureServer {
+	t.Helper()
+	s := &secureServer{}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		s.mu.Unlock()
+		fmt.Fprintf(w, "ret=%s", ret)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// last returns the last request received.
+func (s *secureServer) last(t *testing.T) *http.Request {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		t.Fatal("no request received")
+	}
+	return s.requests[len(s.requests)-1]
+}
+
+func TestRegisterTerminal(t *testing.T) {
+	s := newSecureServer(t, "OK")
+	d := newDaikin(s.Listener.Addr().String())
+	d.Credentials.Password = "pw"
+	ctx := context.Background()
+
+	if err := d.RegisterTerminal(ctx, "0123"); err != nil {
+		t.Fatal(err)
+	}
+	r := s.last(t)
+	if r.TLS == nil || r.URL.Path != "/common/register_terminal" || r.URL.RawQuery != "key=0123&lpw=pw" {
+		t.Errorf("got %s request %s", r.Proto, r.URL)
+	}
+	c := d.Credentials
+	if len(c.UUID) != 32 || c.Key != "0123" || c.Password != "pw" || !c.IsSecure() {
+		t.Errorf("credentials: %+v", c)
+	}
+	if got := r.Header.Get("X-Daikin-uuid"); got != c.UUID {
+		t.Errorf("uuid header %q, want %q", got, c.UUID)
+	}
+
+	// later requests use HTTPS and the terminal id
+	if _, err := d.GetRaw(ctx, "/common/basic_info"); err != nil {
+		t.Fatal(err)
+	}
+	if r := s.last(t); r.TLS == nil || r.Header.Get("X-Daikin-uuid") != c.UUID || r.URL.RawQuery != "lpw=pw" {
+		t.Errorf("got request %s, uuid %q", r.URL, r.Header.Get("X-Daikin-uuid"))
+	}
+}
+
+func TestRegisterTerminalFailed(t *testing.T) {
+	s := newSecureServer(t, "PARAM NG")
+	d := newDaikin(s.Listener.Addr().String())
+	d.Credentials.Password = "pw"
+
+	if err := d.RegisterTerminal(context.Background(), "wrong"); err == nil {
+		t.Fatal("no error for rejected key")
+	}
+	if c := d.Credentials; c != (daikin.Credentials{Password: "pw"}) {
+		t.Errorf("credentials changed: %+v", c)
+	}
+	if err := d.RegisterTerminal(context.Background(), ""); err == nil {
+		t.Error("no error for empty key")
+	}
+}
+
+func TestPasswordQuery(t *testing.T) {
+	s := newSecureServer(t, "OK")
+	d := newDaikin(s.Listener.Addr().String())
+	d.Credentials = daikin.Credentials{Password: "a&b", Secure: true}
+	ctx := context.Background()
+
+	tests := []struct {
+		params map[string]string
+		want   string
+	}{
+		{nil, "lpw=a%26b"},
+		{map[string]string{"pow": "1"}, "pow=1&lpw=a%26b"},
+		// a password in the query is not replaced
+		{map[string]string{"lpw": "other", "pow": "1"}, "lpw=other&pow=1"},
+		{map[string]string{"pow": "1", "xlpw": "x"}, "pow=1&xlpw=x&lpw=a%26b"},
+	}
+	for _, tt := range tests {
+		if _, err := d.SetRaw(ctx, "/aircon/set_control_info", tt.params); err != nil {
+			t.Fatal(err)
+		}
+		r := s.last(t)
+		if got := r.URL.RawQuery; got != tt.want || r.TLS == nil {
+			t.Errorf("%v: got %q, want %q", tt.params, got, tt.want)
+		}
+		if n := strings.Count("&"+r.URL.RawQuery, "&lpw="); n != 1 {
+			t.Errorf("%v: %d passwords", tt.params, n)
+		}
+	}
+}
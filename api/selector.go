@@ -0,0 +1,419 @@
+package daikin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Selector selects devices of a DaikinNetwork. It is a comma separated
+// list of terms, a device is selected if it matches any of them:
+//
+//	all             every device
+//	name:<name>     the name of the unit, ignoring case
+//	mac:<mac>       the MAC address, with or without separators
+//	group:<group>   the members of a group, see GroupsOption
+//	addr:<address>  the address of the unit
+//	<value>         the address, MAC address, name, alias or group
+//
+// Aliases and groups are assigned with DeviceOption and in the
+// Inventory, groups also with GroupsOption.
+type Selector struct {
+	terms []selectorTerm
+}
+
+type selectorTerm struct {
+	kind  string
+	value string
+}
+
+const (
+	selectAll   = "all"
+	selectName  = "name"
+	selectMAC   = "mac"
+	selectGroup = "group"
+	selectAddr  = "addr"
+	// selectAny matches a value without prefix
+	selectAny = ""
+)
+
+// SelectAll selects every device.
+var SelectAll = Selector{terms: []selectorTerm{{kind: selectAll}}}
+
+// ParseSelector parses the selector syntax described at Selector.
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+		if len(t) == 0 {
+			return Selector{}, fmt.Errorf("empty term in selector %q", s)
+		}
+		if t == selectAll {
+			sel.terms = append(sel.terms, selectorTerm{kind: selectAll})
+			continue
+		}
+		kind, value, ok := strings.Cut(t, ":")
+		if !ok || !isSelectorKind(kind) || len(NormalizeMAC(t)) > 0 {
+			// e.g. an address with port or a MAC address
+			sel.terms = append(sel.terms, selectorTerm{kind: selectAny, value: t})
+			continue
+		}
+		switch kind {
+		case selectName, selectGroup, selectAddr:
+		case selectMAC:
+			if len(NormalizeMAC(value)) == 0 {
+				return Selector{}, fmt.Errorf("invalid MAC address %q", value)
+			}
+		default:
+			return Selector{}, fmt.Errorf("unknown selector %q, expected name, mac, group or addr", kind)
+		}
+		if len(value) == 0 {
+			return Selector{}, fmt.Errorf("empty %s in selector %q", kind, s)
+		}
+		sel.terms = append(sel.terms, selectorTerm{kind: kind, value: value})
+	}
+	return sel, nil
+}
+
+func (s Selector) String() string {
+	terms := make([]string, len(s.terms))
+	for i, t := range s.terms {
+		switch t.kind {
+		case selectAll, selectAny:
+			terms[i] = t.kind + t.value
+		default:
+			terms[i] = t.kind + ":" + t.value
+		}
+	}
+	return strings.Join(terms, ",")
+}
+
+// needsInfo returns whether the name or MAC address of the devices is
+// required to match s.
+func (s Selector) needsInfo() bool {
+	for _, t := range s.terms {
+		if t.kind != selectAll && t.kind != selectAddr {
+			return true
+		}
+	}
+	return false
+}
+
+// identity contains the values a selector matches.
+type identity struct {
+	address string
+	name    string
+	mac     string
+	// aliases are assigned with DeviceOption and in the inventory
+	aliases []string
+}
+
+func (s Selector) match(id identity, groups map[string][]string) bool {
+	for _, t := range s.terms {
+		if t.match(id, groups) {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns whether the term matches id. The members of groups are
+// matched as values without prefix, groups cannot be nested.
+func (t selectorTerm) match(id identity, groups map[string][]string) bool {
+	switch t.kind {
+	case selectAll:
+		return true
+	case selectAddr:
+		return id.address == t.value
+	case selectName:
+		return len(id.name) > 0 && strings.EqualFold(id.name, t.value)
+	case selectMAC:
+		return len(id.mac) > 0 && id.mac == NormalizeMAC(t.value)
+	case selectGroup:
+		for _, m := range groups[t.value] {
+			if (selectorTerm{kind: selectAny, value: m}).match(id, nil) {
+				return true
+			}
+		}
+		return false
+	}
+	if id.address == t.value || strings.EqualFold(id.name, t.value) {
+		return true
+	}
+	for _, a := range id.aliases {
+		if strings.EqualFold(a, t.value) {
+			return true
+		}
+	}
+	if mac := NormalizeMAC(t.value); len(mac) > 0 && id.mac == mac {
+		return true
+	}
+	_, ok := groups[t.value]
+	return ok && (selectorTerm{kind: selectGroup, value: t.value}).match(id, groups)
+}
+
+// isSelectorKind returns whether s may be a selector prefix, it
+// consists of letters only.
+func isSelectorKind(s string) bool {
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+// NormalizeMAC returns the MAC address s in the format of the units,
+// 12 upper case hex digits. It returns "" if s is no MAC address.
+func NormalizeMAC(s string) string {
+	s = strings.NewReplacer(":", "", "-", "", ".", "").Replace(s)
+	if len(s) != 12 {
+		return ""
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return ""
+		}
+	}
+	return strings.ToUpper(s)
+}
+
+// MAC returns the MAC address of the unit as 12 upper case hex digits,
+// "" if the unit did not report it.
+func (b *BasicInfo) MAC() string {
+	return NormalizeMAC(b.Extra["mac"])
+}
+
+// targets returns the addresses of the devices, sorted.
+func (d *DaikinNetwork) targets() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	targets := make([]string, 0, len(d.Devices))
+	for t := range d.Devices {
+		targets = append(targets, t)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// forEach calls f for the targets in parallel, at most Concurrency at a
+// time, and waits for all calls to return.
+func (d *DaikinNetwork) forEach(targets []string, f func(target string, dev Device)) {
+	n := d.Concurrency
+	if n < 1 {
+		n = 1
+	}
+	devs := d.List()
+	sem := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			f(t, devs[t])
+		}()
+	}
+	wg.Wait()
+}
+
+// identify returns the name, MAC address and alias of dev. They are taken
+// from the discovery reply or the last state of the device, else the
+// basic info is fetched.
+func (d *DaikinNetwork) identify(ctx context.Context, target string, dev Device) (id identity, err error) {
+	id = identity{address: target}
+	defer func() {
+		if s, ok := d.settingsOf(target, id.mac); ok && len(s.Alias) > 0 {
+			id.aliases = append(id.aliases, s.Alias)
+		}
+		if e, ok := d.inventory.lookupEntry(id.mac); ok && len(e.Alias) > 0 {
+			id.aliases = append(id.aliases, e.Alias)
+		}
+	}()
+	d.mu.RLock()
+	vals, ok := d.discovered[target]
+	d.mu.RUnlock()
+	if ok && len(vals["mac"]) > 0 {
+		var n Name
+		if err := n.decode("name", vals["name"]); err == nil {
+			id.name = n.String()
+		}
+		id.mac = NormalizeMAC(vals["mac"])
+		return id, nil
+	}
+	s := dev.Snapshot()
+	if s.BasicInfo == nil {
+		if dd, ok := dev.(*Daikin); ok {
+			if err := dd.getBasicInfo(ctx); err != nil {
+				return id, err
+			}
+			s = dd.Snapshot()
+		} else {
+			state, err := dev.Refresh(ctx)
+			if state.BasicInfo == nil {
+				if err == nil {
+					err = fmt.Errorf("no basic info")
+				}
+				return id, err
+			}
+			s = state.Snapshot
+		}
+	}
+	id.name = s.BasicInfo.Name.String()
+	id.mac = s.BasicInfo.MAC()
+	return id, nil
+}
+
+// allGroups returns the groups of GroupsOption merged with the groups
+// of DeviceOption and the inventory.
+func (d *DaikinNetwork) allGroups() map[string][]string {
+	groups := map[string][]string{}
+	if d.inventory != nil {
+		groups = d.inventory.Groups()
+	}
+	for g, members := range d.groups {
+		groups[g] = append(groups[g], members...)
+	}
+	for key, s := range d.settings {
+		for _, g := range s.Groups {
+			groups[g] = append(groups[g], key)
+		}
+	}
+	return groups
+}
+
+// Select returns the devices matching sel. Selecting by name, MAC
+// address or group requires the basic info of devices not reported by
+// Discover, it is fetched in parallel. Devices which cannot be
+// identified are skipped, their errors are returned joined together
+// with the selected devices.
+func (d *DaikinNetwork) Select(ctx context.Context, sel Selector) (map[string]Device, error) {
+	groups := d.allGroups()
+	for _, t := range sel.terms {
+		if _, ok := groups[t.value]; t.kind == selectGroup && !ok {
+			return nil, fmt.Errorf("unknown group %q", t.value)
+		}
+	}
+
+	var mu sync.Mutex
+	selected := map[string]Device{}
+	failed := map[string]error{}
+	d.forEach(d.targets(), func(target string, dev Device) {
+		id := identity{address: target}
+		if sel.needsInfo() {
+			var err error
+			if id, err = d.identify(ctx, target, dev); err != nil && !sel.match(id, groups) {
+				mu.Lock()
+				failed[target] = err
+				mu.Unlock()
+				return
+			}
+		}
+		if sel.match(id, groups) {
+			mu.Lock()
+			selected[target] = dev
+			mu.Unlock()
+		}
+	})
+
+	var errs []error
+	for _, t := range d.targets() {
+		if err, ok := failed[t]; ok {
+			errs = append(errs, fmt.Errorf("%s: cannot identify device: %w", t, err))
+		}
+	}
+	return selected, errors.Join(errs...)
+}
+
+// Result is the outcome of Apply for one device.
+type Result struct {
+	Target string
+	// Name is the name of the unit, if known.
+	Name string
+	// ControlInfo is the control info written to the unit.
+	ControlInfo *ControlInfo
+	Err         error
+}
+
+func (r Result) String() string {
+	target := r.Target
+	if len(r.Name) > 0 {
+		target = fmt.Sprintf("%s (%s)", r.Target, r.Name)
+	}
+	if r.Err != nil {
+		return fmt.Sprintf("%s: %v", target, r.Err)
+	}
+	return fmt.Sprintf("%s: ok", target)
+}
+
+// Report contains the results of Apply sorted by target.
+type Report []Result
+
+func (r Report) String() string {
+	lines := make([]string, len(r))
+	for i, res := range r {
+		lines[i] = res.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Failed returns the results with an error.
+func (r Report) Failed() []Result {
+	var failed []Result
+	for _, res := range r {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// Err returns the errors of the devices joined, nil if all changes
+// were applied.
+func (r Report) Err() error {
+	var errs []error
+	for _, res := range r.Failed() {
+		errs = append(errs, fmt.Errorf("%s: %w", res.Target, res.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// Apply applies the changes with ApplyTo to the devices matching sel.
+// The devices are changed in parallel, at most Concurrency at a time.
+// A failure does not stop the changes of the other devices, the outcome
+// per device is returned in the report. The error reports devices which
+// could not be identified, see Select, or that no device matched.
+func (d *DaikinNetwork) Apply(ctx context.Context, sel Selector, changes ...Change) (Report, error) {
+	devs, err := d.Select(ctx, sel)
+	if len(devs) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no device matches %q", sel.String())
+		}
+		return nil, err
+	}
+
+	targets := make([]string, 0, len(devs))
+	for t := range devs {
+		targets = append(targets, t)
+	}
+	sort.Strings(targets)
+
+	report := make(Report, len(targets))
+	index := map[string]int{}
+	for i, t := range targets {
+		index[t] = i
+	}
+	d.forEach(targets, func(target string, dev Device) {
+		ci, err := ApplyTo(ctx, dev, changes...)
+		res := Result{Target: target, ControlInfo: ci, Err: err}
+		if s := dev.Snapshot(); s.BasicInfo != nil {
+			res.Name = s.BasicInfo.Name.String()
+		}
+		report[index[target]] = res
+	})
+	return report, err
+}
@@ -0,0 +1,156 @@
+package daikin_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+// newFakes starts n fake adapters named Unit0, Unit1, ... with the MAC
+// addresses 0011223344A0, 0011223344A1, ...
+func newFakes(t *testing.T, n int) []*daikintest.Server {
+	t.Helper()
+	fakes := make([]*daikintest.Server, n)
+	for i := range fakes {
+		s := daikintest.NewServer()
+		t.Cleanup(s.Close)
+		s.Set("/common/basic_info", "name", "Unit"+string(rune('0'+i)))
+		s.Set("/common/basic_info", "mac", "0011223344A"+string(rune('0'+i)))
+		fakes[i] = s
+	}
+	return fakes
+}
+
+// newNetwork returns a network of the fake adapters and the addresses.
+func newNetwork(t *testing.T, addresses []string, o ...daikin.Option) *daikin.DaikinNetwork {
+	t.Helper()
+	o = append(o, daikin.PolicyOption(testPolicy))
+	for _, a := range addresses {
+		o = append(o, daikin.AddressOption(a))
+	}
+	d, err := daikin.NewNetwork(o...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestSelect(t *testing.T) {
+	fakes := newFakes(t, 3)
+	addr := []string{fakes[0].Address(), fakes[1].Address(), fakes[2].Address()}
+	groups := map[string][]string{"upstairs": {addr[0], "00:11:22:33:44:a2"}}
+
+	tests := []struct {
+		sel  string
+		want []string
+	}{
+		{"all", addr},
+		{"addr:" + addr[1], addr[1:2]},
+		{"name:unit1", addr[1:2]},
+		{"Unit2", addr[2:3]},
+		{"mac:00-11-22-33-44-a1", addr[1:2]},
+		{"0011.2233.44a0", addr[0:1]},
+		{"group:upstairs", []string{addr[0], addr[2]}},
+		{"upstairs,name:Unit1", addr},
+		{"name:other", nil},
+	}
+	for _, tt := range tests {
+		d := newNetwork(t, addr, daikin.GroupsOption(groups))
+		sel, err := daikin.ParseSelector(tt.sel)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sel, err)
+		}
+		devs, err := d.Select(context.Background(), sel)
+		if err != nil {
+			t.Errorf("%s: %v", tt.sel, err)
+		}
+		var got []string
+		for target := range devs {
+			got = append(got, target)
+		}
+		sort.Strings(got)
+		want := append([]string(nil), tt.want...)
+		sort.Strings(want)
+		if len(got) != len(want) {
+			t.Errorf("%s: got %v, want %v", tt.sel, got, want)
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s: got %v, want %v", tt.sel, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	for _, s := range []string{"", "all,", "mac:0011", "name:", "color:red"} {
+		if _, err := daikin.ParseSelector(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestSelectUnknownGroup(t *testing.T) {
+	fakes := newFakes(t, 1)
+	d := newNetwork(t, []string{fakes[0].Address()})
+	sel, _ := daikin.ParseSelector("group:none")
+	if _, err := d.Select(context.Background(), sel); err == nil {
+		t.Error("no error for unknown group")
+	}
+}
+
+func TestNetworkApply(t *testing.T) {
+	fakes := newFakes(t, 3)
+	// nothing listens at the last address
+	down := daikintest.NewServer()
+	down.Close()
+	addr := []string{fakes[0].Address(), fakes[1].Address(), fakes[2].Address(), down.Address()}
+
+	d := newNetwork(t, addr, daikin.ConcurrencyOption(2))
+	report, err := d.Apply(context.Background(), daikin.SelectAll, daikin.WithPower(daikin.PowerOn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report) != len(addr) {
+		t.Fatalf("got %d results, want %d", len(report), len(addr))
+	}
+	for i := 1; i < len(report); i++ {
+		if report[i-1].Target > report[i].Target {
+			t.Errorf("report not sorted: %s", report)
+		}
+	}
+	failed := report.Failed()
+	if len(failed) != 1 || failed[0].Target != down.Address() || report.Err() == nil {
+		t.Errorf("failed: %v", failed)
+	}
+	for i, s := range fakes {
+		if got := s.Get("/aircon/get_control_info", "pow"); got != "1" {
+			t.Errorf("%s: pow is %q, want 1", addr[i], got)
+		}
+	}
+	for _, res := range report {
+		if res.Err == nil && (res.ControlInfo == nil || len(res.Name) == 0) {
+			t.Errorf("%s: control info %v, name %q", res.Target, res.ControlInfo, res.Name)
+		}
+	}
+}
+
+func TestNetworkApplyNoMatch(t *testing.T) {
+	fakes := newFakes(t, 1)
+	d := newNetwork(t, []string{fakes[0].Address()})
+	sel, _ := daikin.ParseSelector("name:other")
+	if report, err := d.Apply(context.Background(), sel, daikin.WithPower(daikin.PowerOn)); err == nil || report != nil {
+		t.Errorf("got report %v, error %v", report, err)
+	}
+	if got := fakes[0].Get("/aircon/get_control_info", "pow"); got != "0" {
+		t.Errorf("pow is %q, want 0", got)
+	}
+}
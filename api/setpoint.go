@@ -0,0 +1,168 @@
+package daikin
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// SetpointRange is the allowed target temperature range of a mode.
+type SetpointRange struct {
+	Min  float64
+	Max  float64
+	Step float64
+}
+
+// DefaultSetpoints are the target temperature ranges of most Daikin
+// split units. They are used for modes without limits in ModelInfo.
+var DefaultSetpoints = map[Mode]SetpointRange{
+	ModeAuto:  {Min: 18, Max: 30, Step: 0.5},
+	ModeAuto1: {Min: 18, Max: 30, Step: 0.5},
+	ModeAuto7: {Min: 18, Max: 30, Step: 0.5},
+	ModeCool:  {Min: 18, Max: 32, Step: 0.5},
+	ModeHeat:  {Min: 10, Max: 30, Step: 0.5},
+}
+
+// Check returns an error if t is outside of the range or not a multiple
+// of the step size.
+func (r SetpointRange) Check(t float64) error {
+	if t < r.Min || t > r.Max {
+		return fmt.Errorf("temperature %s out of range %s-%s",
+			formatSetpoint(t), formatSetpoint(r.Min), formatSetpoint(r.Max))
+	}
+	if r.Step > 0 {
+		if n := t / r.Step; math.Abs(n-math.Round(n)) > 1e-9 {
+			return fmt.Errorf("temperature %s is not a multiple of %s",
+				formatSetpoint(t), formatSetpoint(r.Step))
+		}
+	}
+	return nil
+}
+
+func formatSetpoint(t float64) string {
+	return strconv.FormatFloat(t, 'f', -1, 64)
+}
+
+// SetpointRangeFor returns the target temperature range of mode. Limits
+// in m override the defaults. ok is false for modes without a target
+// temperature (fan and dehumidify).
+func SetpointRangeFor(mode Mode, m *ModelInfo) (r SetpointRange, ok bool) {
+	if m != nil {
+		if r, ok = m.Setpoints[mode]; ok {
+			return r, true
+		}
+	}
+	r, ok = DefaultSetpoints[mode]
+	return r, ok
+}
+
+// CheckTemperature returns an error if t is not a valid target
+// temperature in any of the modes. Without modes, all modes with a
+// target temperature are checked. It allows validating user input
+// before the current mode of the unit is known.
+func CheckTemperature(t float64, m *ModelInfo, modes ...Mode) error {
+	if len(modes) == 0 {
+		modes = []Mode{ModeAuto, ModeCool, ModeHeat}
+	}
+	var err error
+	var union SetpointRange
+	for i, mode := range modes {
+		r, ok := SetpointRangeFor(mode, m)
+		if !ok {
+			return fmt.Errorf("mode %s has no target temperature", mode.String())
+		}
+		if err = r.Check(t); err == nil {
+			return nil
+		}
+		if i == 0 || r.Min < union.Min {
+			union.Min = r.Min
+		}
+		if i == 0 || r.Max > union.Max {
+			union.Max = r.Max
+		}
+	}
+	if len(modes) > 1 && union.Check(t) != nil {
+		// report the range of all modes, not of the last one
+		return union.Check(t)
+	}
+	return err
+}
+
+// Validate checks that all settings have values known to the units and
+// that the target temperature and humidity are valid in the mode. Limits
+// in m override the defaults, m may be nil.
+func (c *ControlInfo) Validate(m *ModelInfo) error {
+	return c.ValidateChange(nil, m)
+}
+
+// ValidateChange is like Validate, but only checks the settings which
+// differ from old, the settings reported by the unit. Settings of the
+// unit are accepted as they are, even outside of the default limits, so
+// that e.g. a unit heating to 31° can be switched off. The target
+// temperature is checked if it or the mode changed. If old is nil, all
+// settings are checked.
+func (c *ControlInfo) ValidateChange(old *ControlInfo, m *ModelInfo) error {
+	all := old == nil
+	if all || c.Power != old.Power {
+		if _, ok := powerMap[c.Power]; !ok {
+			return fmt.Errorf("unknown power value: %d", int(c.Power))
+		}
+	}
+	if all || c.Mode != old.Mode {
+		if _, ok := modeMap[c.Mode]; !ok {
+			return fmt.Errorf("unknown mode value: %d", int(c.Mode))
+		}
+	}
+	if all || c.Fan != old.Fan {
+		if _, ok := fanMap[c.Fan]; !ok {
+			return fmt.Errorf("unknown fan value: %s", string(c.Fan))
+		}
+	}
+	if all || c.FanDir != old.FanDir || c.FanDirUD != old.FanDirUD || c.FanDirLR != old.FanDirLR {
+		if err := c.validateFanDir(m); err != nil {
+			return err
+		}
+	}
+
+	if all || c.Mode != old.Mode || c.Temperature.encode() != old.Temperature.encode() {
+		if r, ok := SetpointRangeFor(c.Mode, m); ok {
+			if !c.Temperature.IsSet() {
+				return fmt.Errorf("mode %s needs a target temperature", c.Mode.String())
+			}
+			if err := r.Check(c.Temperature.value); err != nil {
+				return fmt.Errorf("mode %s: %v", c.Mode.String(), err)
+			}
+		} else if v := c.Temperature.encode(); v != TemperatureModeDefault && v != TemperatureNone {
+			return fmt.Errorf("mode %s has no target temperature, got %s",
+				c.Mode.String(), c.Temperature.String())
+		}
+	}
+
+	if all || c.Humidity.encode() != old.Humidity.encode() {
+		if c.Humidity.IsSet() && (c.Humidity.value < 0 || c.Humidity.value > 100) {
+			return fmt.Errorf("humidity %d out of range 0-100", c.Humidity.value)
+		}
+	}
+	return nil
+}
+
+// validateFanDir checks the louvre settings against the louvres of the
+// unit.
+func (c *ControlInfo) validateFanDir(m *ModelInfo) error {
+	if _, ok := fanDirMap[c.FanDir]; !ok {
+		return fmt.Errorf("unknown f_dir value: %d", int(c.FanDir))
+	}
+	if m != nil && !slices.Contains(m.FanDirs(), c.FanDir) {
+		return fmt.Errorf("fan louvre %s not supported by the unit", c.FanDir.String())
+	}
+	// units may report only one of the separate louvres
+	ud, lr := c.FanDir.Swing()
+	if c.FanDirUD.IsSet() && ud != c.FanDirUD {
+		return fmt.Errorf("f_dir=%d does not match f_dir_ud=%s", int(c.FanDir), string(c.FanDirUD))
+	}
+	if c.FanDirLR.IsSet() && lr != c.FanDirLR {
+		return fmt.Errorf("f_dir=%d does not match f_dir_lr=%s", int(c.FanDir), string(c.FanDirLR))
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package daikin_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+func TestValidateOnlyChanges(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+	// heating to 31°, above the default range
+	s.Set("/aircon/get_control_info", "pow", "1")
+	s.Set("/aircon/get_control_info", "mode", "4")
+	s.Set("/aircon/get_control_info", "stemp", "31.0")
+
+	d := newDaikin(s.Address())
+	ctx := context.Background()
+	if _, err := d.Apply(ctx, daikin.WithPower(daikin.PowerOff)); err != nil {
+		t.Fatalf("switching off: %v", err)
+	}
+	if got := s.Get("/aircon/get_control_info", "pow"); got != "0" {
+		t.Errorf("pow is %q, want 0", got)
+	}
+	if got := s.Get("/aircon/get_control_info", "stemp"); got != "31.0" {
+		t.Errorf("stemp is %q, want 31.0", got)
+	}
+
+	_, err := d.Apply(ctx, daikin.WithTemperature(30.5))
+	if err == nil || !strings.Contains(err.Error(), "out of range 10-30") {
+		t.Errorf("setting 30.5 in heat mode: got %v, want range error", err)
+	}
+	if n := count(s, "/aircon/set_control_info"); n != 1 {
+		t.Errorf("%d writes, want only the first one", n)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		values map[string]string
+		err    string
+	}{
+		{map[string]string{"pow": "1", "mode": "3", "stemp": "25.0", "f_rate": "A", "f_dir": "0"}, ""},
+		{map[string]string{"pow": "1", "mode": "3", "stemp": "25.3", "f_rate": "A", "f_dir": "0"}, "not a multiple of 0.5"},
+		{map[string]string{"pow": "1", "mode": "4", "stemp": "32.0", "f_rate": "A", "f_dir": "0"}, "out of range 10-30"},
+		{map[string]string{"pow": "1", "mode": "3", "stemp": "--", "f_rate": "A", "f_dir": "0"}, "needs a target temperature"},
+		{map[string]string{"pow": "1", "mode": "6", "stemp": "--", "f_rate": "A", "f_dir": "0"}, ""},
+		{map[string]string{"pow": "1", "mode": "2", "stemp": "M", "shum": "AUTO", "f_rate": "A", "f_dir": "0"}, ""},
+		{map[string]string{"pow": "1", "mode": "6", "stemp": "22.0", "f_rate": "A", "f_dir": "0"}, "has no target temperature"},
+		{map[string]string{"pow": "1", "mode": "3", "stemp": "25.0", "shum": "120", "f_rate": "A", "f_dir": "0"}, "humidity 120 out of range"},
+	}
+	for _, tt := range tests {
+		s := daikintest.NewServer()
+		setValues(s, "/aircon/get_control_info", tt.values)
+		d := newDaikin(s.Address())
+		if err := d.GetControlInfo(); err != nil {
+			t.Fatal(err)
+		}
+		err := d.ControlInfo.Validate(nil)
+		if len(tt.err) == 0 && err != nil || len(tt.err) > 0 && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%v: got %v, want %q", tt.values, err, tt.err)
+		}
+		s.Close()
+	}
+}
+
+func TestModelInfoSetpoints(t *testing.T) {
+	s := daikintest.NewAirBaseServer()
+	defer s.Close()
+
+	d := newDaikin(s.Address())
+	ctx := context.Background()
+	if _, err := d.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := daikin.SetpointRangeFor(daikin.ModeCool, d.ModelInfo); r.Min != 16 || r.Max != 32 {
+		t.Errorf("cool range is %v, want 16-32 from the model info", r)
+	}
+	// 17° is below the default range
+	if _, err := d.Apply(ctx, daikin.WithTemperature(17)); err != nil {
+		t.Errorf("setting 17° in cool mode: %v", err)
+	}
+	// AirBase units have no louvre control
+	if _, err := d.Apply(ctx, daikin.WithFanDir(daikin.FanDirVertical)); err == nil {
+		t.Error("louvre set on unit without louvre control")
+	}
+
+	// limits set by the caller are kept
+	d.ModelInfo.Setpoints[daikin.ModeHeat] = daikin.SetpointRange{Min: 5, Max: 20, Step: 1}
+	if err := d.GetModelInfo(); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := daikin.SetpointRangeFor(daikin.ModeHeat, d.ModelInfo); r.Min != 5 || r.Max != 20 {
+		t.Errorf("heat range is %v, want 5-20 set by the caller", r)
+	}
+}
+
+func TestModelInfoWithoutFanDir(t *testing.T) {
+	ci := &daikin.ControlInfo{Mode: daikin.ModeFan, Fan: daikin.FanAuto, FanDir: daikin.FanDirVertical}
+	ci.Temperature.Set(daikin.TemperatureNone)
+	// the unit did not report en_fdir
+	if err := ci.Validate(&daikin.ModelInfo{}); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,6 +1,7 @@
 package daikin
 
 import (
+	"math"
 )
 
 // String is a generic class for string values
@@ -13,8 +14,13 @@ func (s *String) String() string {
 	return s.value
 }
 
-func (s *String) setUrlValues() string {
-	return s.param + "=" + s.String()
+func (s *String) encode() string {
+	return s.value
+}
+
+// Float64 returns NaN, strings are not numeric.
+func (s *String) Float64() float64 {
+	return math.NaN()
 }
 
 func (s *String) decode(param string, str string) error {
@@ -22,3 +28,13 @@ func (s *String) decode(param string, str string) error {
 	return nil
 }
 
+// MarshalText returns the value.
+func (s String) MarshalText() ([]byte, error) {
+	return []byte(s.value), nil
+}
+
+// UnmarshalText sets the value.
+func (s *String) UnmarshalText(text []byte) error {
+	s.value = string(text)
+	return nil
+}
@@ -0,0 +1,113 @@
+package daikin
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Swing is the setting of one louvre of units with 3D airflow, which
+// report the vertical and horizontal swing separately with f_dir_ud and
+// f_dir_lr. The empty value means the unit has no such setting.
+type Swing string
+
+// Swing values.
+const (
+	SwingStopped Swing = "0"
+	SwingOn      Swing = "S"
+)
+
+var swingMap = map[Swing]string{
+	SwingStopped: "Stopped",
+	SwingOn:      "Swing",
+}
+
+func (s *Swing) encode() string {
+	return string(*s)
+}
+
+func (s *Swing) decode(param string, v string) error {
+	if _, ok := swingMap[Swing(v)]; !ok {
+		return fmt.Errorf("unknown %s value: %s", param, v)
+	}
+	*s = Swing(v)
+	return nil
+}
+
+// IsSet returns false if the unit has no separate louvre setting.
+func (s *Swing) IsSet() bool {
+	return len(*s) > 0
+}
+
+func (s *Swing) String() string {
+	if !s.IsSet() {
+		return "-"
+	}
+	v, ok := swingMap[*s]
+	if !ok {
+		return fmt.Sprintf("Unknown Swing [%v]", *s)
+	}
+	return v
+}
+
+// Float64 returns 0 for stopped and 1 for swing.
+func (s *Swing) Float64() float64 {
+	switch *s {
+	case SwingStopped:
+		return 0.0
+	case SwingOn:
+		return 1.0
+	}
+	return math.NaN()
+}
+
+// MarshalText returns "stopped" or "swing".
+func (s Swing) MarshalText() ([]byte, error) {
+	if v, ok := swingMap[s]; ok {
+		return []byte(strings.ToLower(v)), nil
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalText accepts the symbolic name or the value of the unit
+// (0, S).
+func (s *Swing) UnmarshalText(text []byte) error {
+	for k, v := range swingMap {
+		if strings.EqualFold(v, string(text)) {
+			*s = k
+			return nil
+		}
+	}
+	return s.decode("swing", string(text))
+}
+
+// UnmarshalJSON accepts a string or a number.
+func (s *Swing) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, s)
+}
+
+// Swing returns the vertical (f_dir_ud) and horizontal (f_dir_lr) swing
+// of the combined louvre setting.
+func (f FanDir) Swing() (ud Swing, lr Swing) {
+	ud, lr = SwingStopped, SwingStopped
+	if f&FanDirVertical != 0 {
+		ud = SwingOn
+	}
+	if f&FanDirHorizontal != 0 {
+		lr = SwingOn
+	}
+	return ud, lr
+}
+
+// FanDirOf returns the combined louvre setting (f_dir) of the vertical
+// and horizontal swing.
+func FanDirOf(ud Swing, lr Swing) FanDir {
+	f := FanDirStopped
+	if ud == SwingOn {
+		f |= FanDirVertical
+	}
+	if lr == SwingOn {
+		f |= FanDirHorizontal
+	}
+	return f
+}
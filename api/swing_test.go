@@ -0,0 +1,109 @@
+package daikin_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+func TestFanDirSwing(t *testing.T) {
+	tests := []struct {
+		f      daikin.FanDir
+		ud, lr daikin.Swing
+	}{
+		{daikin.FanDirStopped, daikin.SwingStopped, daikin.SwingStopped},
+		{daikin.FanDirVertical, daikin.SwingOn, daikin.SwingStopped},
+		{daikin.FanDirHorizontal, daikin.SwingStopped, daikin.SwingOn},
+		{daikin.FanDirBoth, daikin.SwingOn, daikin.SwingOn},
+	}
+	for _, tt := range tests {
+		if ud, lr := tt.f.Swing(); ud != tt.ud || lr != tt.lr {
+			t.Errorf("%s: got %q, %q", tt.f.String(), ud, lr)
+		}
+		if f := daikin.FanDirOf(tt.ud, tt.lr); f != tt.f {
+			t.Errorf("%q, %q: got %s", tt.ud, tt.lr, f.String())
+		}
+	}
+}
+
+func TestSwingDecode(t *testing.T) {
+	tests := []struct {
+		values map[string]string
+		fanDir daikin.FanDir
+		ud, lr daikin.Swing
+		err    string
+	}{
+		{map[string]string{"f_dir": "0"}, daikin.FanDirStopped, "", "", ""},
+		{map[string]string{"f_dir": "3", "f_dir_ud": "S", "f_dir_lr": "S"}, daikin.FanDirBoth, daikin.SwingOn, daikin.SwingOn, ""},
+		// the separate louvres take precedence if both are reported
+		{map[string]string{"f_dir": "0", "f_dir_ud": "S", "f_dir_lr": "0"}, daikin.FanDirVertical, daikin.SwingOn, daikin.SwingStopped, ""},
+		// a single louvre leaves f_dir as reported
+		{map[string]string{"f_dir": "2", "f_dir_lr": "S"}, daikin.FanDirHorizontal, "", daikin.SwingOn, ""},
+		{map[string]string{"f_dir": "0", "f_dir_ud": "X"}, 0, "", "", "unknown f_dir_ud value: X"},
+	}
+	for _, tt := range tests {
+		s := daikintest.NewServer()
+		setValues(s, "/aircon/get_control_info", tt.values)
+		d := newDaikin(s.Address())
+		err := d.GetControlInfo()
+		switch {
+		case len(tt.err) > 0:
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%v: got %v, want %q", tt.values, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%v: %v", tt.values, err)
+		case d.ControlInfo.FanDir != tt.fanDir || d.ControlInfo.FanDirUD != tt.ud || d.ControlInfo.FanDirLR != tt.lr:
+			t.Errorf("%v: got %s, %q, %q", tt.values, d.ControlInfo.FanDir.String(),
+				d.ControlInfo.FanDirUD, d.ControlInfo.FanDirLR)
+		}
+		s.Close()
+	}
+}
+
+func TestValidateFanDir(t *testing.T) {
+	tests := []struct {
+		fanDir daikin.FanDir
+		ud, lr daikin.Swing
+		err    string
+	}{
+		{daikin.FanDirBoth, "", "", ""},
+		{daikin.FanDirBoth, daikin.SwingOn, daikin.SwingOn, ""},
+		{daikin.FanDirVertical, daikin.SwingOn, "", ""},
+		{daikin.FanDirHorizontal, "", daikin.SwingOn, ""},
+		{daikin.FanDirVertical, daikin.SwingStopped, "", "does not match f_dir_ud"},
+		{daikin.FanDirVertical, "", daikin.SwingOn, "does not match f_dir_lr"},
+		{daikin.FanDirStopped, daikin.SwingStopped, daikin.SwingOn, "does not match f_dir_lr"},
+		{daikin.FanDir(7), "", "", "unknown f_dir value"},
+	}
+	for _, tt := range tests {
+		ci := &daikin.ControlInfo{Mode: daikin.ModeFan, Fan: daikin.FanAuto,
+			FanDir: tt.fanDir, FanDirUD: tt.ud, FanDirLR: tt.lr}
+		ci.Temperature.Set(daikin.TemperatureNone)
+		err := ci.Validate(nil)
+		if len(tt.err) == 0 && err != nil || len(tt.err) > 0 && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%d, %q, %q: got %v, want %q", int(tt.fanDir), tt.ud, tt.lr, err, tt.err)
+		}
+	}
+}
+
+func TestSwingSingleLouvre(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+	// the unit reports only the vertical louvre
+	s.Set("/aircon/get_control_info", "f_dir_ud", "0")
+
+	d := newDaikin(s.Address())
+	if _, err := d.Apply(context.Background(), daikin.WithVerticalSwing(daikin.SwingOn)); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Get("/aircon/get_control_info", "f_dir_ud"); got != "S" {
+		t.Errorf("f_dir_ud is %q, want S", got)
+	}
+	if got := s.Get("/aircon/get_control_info", "f_dir_lr"); len(got) > 0 {
+		t.Errorf("f_dir_lr %q sent to a unit without it", got)
+	}
+}
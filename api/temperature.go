@@ -1,45 +1,156 @@
 package daikin
 
 import (
+	"encoding/json"
         "fmt"
+	"math"
         "strconv"
 )
 
-// Temperature in Celcius.
+// Temperature in Celcius. The units report "--" if a sensor is not
+// available and "M" as set temperature of modes without one.
 type Temperature struct {
 	value float64
 	param string
+	// raw holds the non-numeric value as received, it is written
+	// back verbatim.
+	raw string
+	set bool
 }
 
-func (t *Temperature) setUrlValues() string {
-     	return t.param + "=" + t.String()
+// The non-numeric set temperatures reported in fan and dehumidify mode.
+const (
+	TemperatureModeDefault = "M"
+	TemperatureNone        = "--"
+)
+
+// encode returns the value as received or, if the temperature is not
+// set, the placeholder "--" of the units.
+func (t *Temperature) encode() string {
+	if len(t.raw) > 0 {
+		return t.raw
+	}
+	if !t.set {
+		return TemperatureNone
+	}
+	return strconv.FormatFloat(t.value, 'f', 1, 64)
 }
 
 func (t *Temperature) decode(param string, v string) error {
-
-	if v == "--" {
-	   v = "-1"
-	} 
+	switch v {
+	case TemperatureModeDefault, TemperatureNone, "-":
+		*t = Temperature{param: param, raw: v}
+		return nil
+	}
 
 	val, err := strconv.ParseFloat(v, 64)
 	if err != nil {
 		return fmt.Errorf("Temperature: error parsing %s=%s: %v", param, v, err)
 	}
-	*t = Temperature{value: val, param: param}
+	*t = Temperature{value: val, param: param, set: true}
 	return nil
 }
 
+// NewTemperature returns the set temperature v given in unit, rounded
+// to the 0.5°C resolution of the units.
+func NewTemperature(v float64, unit TemperatureUnit) Temperature {
+	return Temperature{value: roundHalf(unit.toCelsius(v)), param: "stemp", set: true}
+}
+
 func (t *Temperature) Set(v string) error {
 	return t.decode(t.param, v)
 }
 
+// IsSet returns true if the temperature has a numeric value.
+func (t *Temperature) IsSet() bool {
+	return t.set
+}
+
+// IsModeDefault returns true if the unit reported "M", the set
+// temperature of the mode is used.
+func (t *Temperature) IsModeDefault() bool {
+	return t.raw == TemperatureModeDefault
+}
+
+// Value returns the temperature, or 0 if it is not set.
+func (t *Temperature) Value() float64 {
+	return t.value
+}
+
 func (t *Temperature) String() string {
-        if t.value == -1 {
+	if t.IsModeDefault() {
+		return "Mode default"
+	}
+	if !t.IsSet() {
                 return "N/A"
         }
 	return strconv.FormatFloat(t.value, 'f', 1, 64)
 }
 
+// In returns the temperature converted to unit, or 0 if it is not set.
+func (t *Temperature) In(unit TemperatureUnit) float64 {
+	if !t.IsSet() {
+		return 0
+	}
+	return unit.fromCelsius(t.value)
+}
+
+// Format returns the temperature in unit with the unit symbol, e.g.
+// "22.5°C" or "72°F". Fahrenheit is rounded to whole degrees, which is
+// close to the 0.5°C resolution of the units.
+func (t *Temperature) Format(unit TemperatureUnit) string {
+	if !t.IsSet() {
+		return t.String()
+	}
+	if unit == Fahrenheit {
+		return strconv.FormatFloat(math.Round(t.In(unit)), 'f', 0, 64) + unit.String()
+	}
+	return strconv.FormatFloat(t.value, 'f', 1, 64) + unit.String()
+}
+
+// Float64 returns the temperature, or NaN if it is not set.
 func (t *Temperature) Float64() float64 {
+	if !t.IsSet() {
+		return math.NaN()
+	}
 	return t.value
 }
+
+// MarshalText returns the temperature in Celsius, or the non-numeric
+// value as received from the unit.
+func (t Temperature) MarshalText() ([]byte, error) {
+	if !t.set {
+		return []byte(t.raw), nil
+	}
+	return []byte(strconv.FormatFloat(t.value, 'f', -1, 64)), nil
+}
+
+// UnmarshalText parses a temperature in Celsius, "M" or "--".
+func (t *Temperature) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*t = Temperature{param: t.param}
+		return nil
+	}
+	return t.decode(t.param, string(text))
+}
+
+// MarshalJSON encodes the temperature as number, "M" as string and
+// missing values as null.
+func (t Temperature) MarshalJSON() ([]byte, error) {
+	switch {
+	case t.set:
+		return []byte(strconv.FormatFloat(t.value, 'f', -1, 64)), nil
+	case len(t.raw) > 0 && t.raw != TemperatureNone && t.raw != "-":
+		return json.Marshal(t.raw)
+	}
+	return []byte("null"), nil
+}
+
+// UnmarshalJSON accepts a number, a string or null.
+func (t *Temperature) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*t = Temperature{param: t.param, raw: TemperatureNone}
+		return nil
+	}
+	return unmarshalJSONText(data, t)
+}
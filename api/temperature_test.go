@@ -0,0 +1,48 @@
+package daikin_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+func TestUnsetValuesOnTheWire(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+
+	d := newDaikin(s.Address())
+	ci := &daikin.ControlInfo{Power: daikin.PowerOn, Mode: daikin.ModeFan, Fan: daikin.FanAuto}
+	if err := d.SetControl(context.Background(), ci); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"stemp", "shum"} {
+		if got := s.Get("/aircon/get_control_info", k); got != "--" {
+			t.Errorf("%s is %q, want --", k, got)
+		}
+	}
+}
+
+func TestRawValuesRoundTrip(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+	s.Set("/aircon/get_control_info", "mode", "2")
+	s.Set("/aircon/get_control_info", "stemp", "M")
+	s.Set("/aircon/get_control_info", "shum", "AUTO")
+
+	d := newDaikin(s.Address())
+	ci, err := d.Apply(context.Background(), daikin.WithPower(daikin.PowerOn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ci.Temperature.IsModeDefault() || ci.Temperature.IsSet() || !ci.Humidity.IsAuto() {
+		t.Errorf("temperature %s, humidity %s", ci.Temperature.String(), ci.Humidity.String())
+	}
+	if got := s.Get("/aircon/get_control_info", "stemp"); got != "M" {
+		t.Errorf("stemp is %q, want M", got)
+	}
+	if got := s.Get("/aircon/get_control_info", "shum"); got != "AUTO" {
+		t.Errorf("shum is %q, want AUTO", got)
+	}
+}
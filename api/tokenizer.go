@@ -0,0 +1,268 @@
+package daikin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// ParseOptions controls how the key=value replies of the units are
+// parsed.
+type ParseOptions struct {
+	// MaxSize is the maximum size of a reply in bytes, 0 for no limit.
+	MaxSize int
+	// MaxFields is the maximum number of fields of a reply, 0 for no
+	// limit.
+	MaxFields int
+	// Lenient skips fields without "=" or key and empty fields,
+	// trims blanks around keys and values and accepts unterminated
+	// quotes and text after a closing quote instead of returning an
+	// error. It helps with adapters sending malformed replies.
+	Lenient bool
+}
+
+// DefaultParseOptions is used if Daikin.ParseOptions is nil. The
+// replies of the units are less than 2 KiB.
+var DefaultParseOptions = ParseOptions{
+	MaxSize:   64 << 10,
+	MaxFields: 1024,
+}
+
+// SyntaxError describes a malformed reply.
+type SyntaxError struct {
+	// Offset is the byte offset of the error in the reply.
+	Offset int
+	// Line and Column are the 1-based position of the error, the
+	// column is counted in bytes.
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// Field is a key=value pair of a reply.
+type Field struct {
+	Key string
+	// Value is the value without quotes. It is not percent-decoded.
+	Value string
+	// Offset is the byte offset of the field in the reply.
+	Offset int
+}
+
+// Tokenizer splits a reply into fields. Fields are separated by commas
+// or line breaks. A value, or the whole field as in CSV, may be put in
+// double quotes to contain commas and line breaks, a double quote in
+// quotes is written as "".
+type Tokenizer struct {
+	opts ParseOptions
+	body []byte
+	pos  int
+	// sep is the separator before pos, 0 at the start
+	sep    byte
+	fields int
+	err    error
+}
+
+// NewTokenizer returns a tokenizer for body.
+func NewTokenizer(body []byte, opts ParseOptions) *Tokenizer {
+	t := &Tokenizer{opts: opts, body: body}
+	if opts.MaxSize > 0 && len(body) > opts.MaxSize {
+		t.err = t.errorf(opts.MaxSize, "reply exceeds %d bytes", opts.MaxSize)
+	}
+	return t
+}
+
+// errorf returns a SyntaxError at offset.
+func (t *Tokenizer) errorf(offset int, format string, args ...interface{}) error {
+	line := 1 + bytes.Count(t.body[:offset], []byte{'\n'})
+	col := offset + 1
+	if i := bytes.LastIndexByte(t.body[:offset], '\n'); i >= 0 {
+		col = offset - i
+	}
+	return &SyntaxError{Offset: offset, Line: line, Column: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Next returns the next field. It returns io.EOF after the last field
+// and a *SyntaxError if the reply is malformed. After an error, Next
+// returns the same error again.
+func (t *Tokenizer) Next() (Field, error) {
+	for t.err == nil {
+		if t.pos >= len(t.body) {
+			if t.sep == ',' && !t.opts.Lenient {
+				t.err = t.errorf(t.pos, "empty field")
+				break
+			}
+			t.err = io.EOF
+			break
+		}
+		start := t.pos
+		field, ok, err := t.field()
+		if err != nil {
+			t.err = err
+			break
+		}
+		if !ok {
+			continue
+		}
+		t.fields++
+		if t.opts.MaxFields > 0 && t.fields > t.opts.MaxFields {
+			t.err = t.errorf(start, "reply exceeds %d fields", t.opts.MaxFields)
+			break
+		}
+		return field, nil
+	}
+	return Field{}, t.err
+}
+
+// field scans the field at pos including its separator. ok is false
+// for fields which are skipped.
+func (t *Tokenizer) field() (f Field, ok bool, err error) {
+	start, prev := t.pos, t.sep
+
+	var text []byte
+	quoted := false
+	if t.body[t.pos] == '"' {
+		// the whole field is quoted as in CSV
+		if text, err = t.quoted(); err != nil {
+			return f, false, err
+		}
+		quoted = true
+	} else {
+		end := t.pos
+		for end < len(t.body) && !isSeparator(t.body[end]) && t.body[end] != '=' {
+			end++
+		}
+		text = t.body[t.pos:end]
+		t.pos = end
+		if end < len(t.body) && t.body[end] == '=' {
+			t.pos++
+			if t.pos < len(t.body) && t.body[t.pos] == '"' {
+				value, err := t.quoted()
+				if err != nil {
+					return f, false, err
+				}
+				text = append(append(append([]byte{}, text...), '='), value...)
+				quoted = true
+			} else {
+				for t.pos < len(t.body) && !isSeparator(t.body[t.pos]) {
+					t.pos++
+				}
+				text = t.body[start:t.pos]
+			}
+		}
+	}
+	if quoted && t.pos < len(t.body) && !isSeparator(t.body[t.pos]) {
+		if !t.opts.Lenient {
+			return f, false, t.errorf(t.pos, "unexpected %q after quoted text", t.body[t.pos])
+		}
+		rest := t.pos
+		for t.pos < len(t.body) && !isSeparator(t.body[t.pos]) {
+			t.pos++
+		}
+		text = append(append([]byte{}, text...), t.body[rest:t.pos]...)
+	}
+	t.separator()
+
+	if len(text) == 0 && !quoted {
+		// blank lines are allowed, empty fields between commas not
+		if t.opts.Lenient || (prev != ',' && t.sep != ',') {
+			return f, false, nil
+		}
+		return f, false, t.errorf(start, "empty field")
+	}
+	i := bytes.IndexByte(text, '=')
+	if i < 0 {
+		if t.opts.Lenient {
+			return f, false, nil
+		}
+		return f, false, t.errorf(start, "missing '=' in field %q", text)
+	}
+	key, value := text[:i], text[i+1:]
+	if t.opts.Lenient {
+		key, value = bytes.Trim(key, " \t"), bytes.Trim(value, " \t")
+	}
+	if len(key) == 0 {
+		if t.opts.Lenient {
+			return f, false, nil
+		}
+		return f, false, t.errorf(start, "empty key in field %q", text)
+	}
+	return Field{Key: string(key), Value: string(value), Offset: start}, true, nil
+}
+
+// quoted scans the quoted text at pos and returns it without quotes.
+func (t *Tokenizer) quoted() ([]byte, error) {
+	open := t.pos
+	var text []byte
+	t.pos++
+	for {
+		i := bytes.IndexByte(t.body[t.pos:], '"')
+		if i < 0 {
+			if !t.opts.Lenient {
+				return nil, t.errorf(open, "unterminated quote")
+			}
+			text = append(text, t.body[t.pos:]...)
+			t.pos = len(t.body)
+			return text, nil
+		}
+		text = append(text, t.body[t.pos:t.pos+i]...)
+		t.pos += i + 1
+		if t.pos < len(t.body) && t.body[t.pos] == '"' {
+			text = append(text, '"')
+			t.pos++
+			continue
+		}
+		return text, nil
+	}
+}
+
+// separator skips the separator at pos and records it.
+func (t *Tokenizer) separator() {
+	t.sep = 0
+	if t.pos >= len(t.body) {
+		return
+	}
+	switch t.body[t.pos] {
+	case ',':
+		t.sep = ','
+		t.pos++
+	case '\r':
+		t.sep = '\n'
+		t.pos++
+		if t.pos < len(t.body) && t.body[t.pos] == '\n' {
+			t.pos++
+		}
+	case '\n':
+		t.sep = '\n'
+		t.pos++
+	}
+}
+
+func isSeparator(c byte) bool {
+	return c == ',' || c == '\n' || c == '\r'
+}
+
+// Parse returns the key/value pairs of body. If a key is repeated, the
+// last value is used.
+func (o ParseOptions) Parse(body []byte) (map[string]string, error) {
+	values := map[string]string{}
+	t := NewTokenizer(body, o)
+	for {
+		f, err := t.Next()
+		if err == io.EOF {
+			return values, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[f.Key] = f.Value
+	}
+}
+
+// ParseValues parses a reply with DefaultParseOptions.
+func ParseValues(body []byte) (map[string]string, error) {
+	return DefaultParseOptions.Parse(body)
+}
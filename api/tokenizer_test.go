@@ -0,0 +1,155 @@
+package daikin
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseValues(t *testing.T) {
+	tests := []struct {
+		body string
+		want map[string]string
+	}{
+		{"ret=OK,pow=1,mode=3", map[string]string{"ret": "OK", "pow": "1", "mode": "3"}},
+		{"ret=OK,adv=,id=", map[string]string{"ret": "OK", "adv": "", "id": ""}},
+		{"ret=OK,cur=2023/1/15 21:26:49", map[string]string{"ret": "OK", "cur": "2023/1/15 21:26:49"}},
+		{"ret=OK,a=1\r\nb=2\n", map[string]string{"ret": "OK", "a": "1", "b": "2"}},
+		{`ret=OK,name="Living, room",x=1`, map[string]string{"ret": "OK", "name": "Living, room", "x": "1"}},
+		{`"ret=OK","name=a ""b"", c"`, map[string]string{"ret": "OK", "name": `a "b", c`}},
+		{"ret=OK,a=x=y", map[string]string{"ret": "OK", "a": "x=y"}},
+		{"", map[string]string{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseValues([]byte(tt.body))
+		if err != nil {
+			t.Errorf("ParseValues(%q): %v", tt.body, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseValues(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		body         string
+		opts         ParseOptions
+		line, column int
+	}{
+		{"ret=OK,pow", ParseOptions{}, 1, 8},
+		{"ret=OK\npow=1,=2", ParseOptions{}, 2, 7},
+		{"ret=OK,,a=1", ParseOptions{}, 1, 8},
+		{"ret=OK,a=1,", ParseOptions{}, 1, 12},
+		{`ret=OK,name="a,b`, ParseOptions{}, 1, 13},
+		{`ret=OK,name="a"b`, ParseOptions{}, 1, 16},
+		{"ret=OK,a=1,b=2", ParseOptions{MaxFields: 2}, 1, 12},
+		{"ret=OK,a=1,b=2", ParseOptions{MaxSize: 10, Lenient: true}, 1, 11},
+	}
+	for _, tt := range tests {
+		_, err := tt.opts.Parse([]byte(tt.body))
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("Parse(%q) = %v, want SyntaxError", tt.body, err)
+			continue
+		}
+		if se.Line != tt.line || se.Column != tt.column {
+			t.Errorf("Parse(%q): error at %d:%d, want %d:%d (%v)",
+				tt.body, se.Line, se.Column, tt.line, tt.column, err)
+		}
+	}
+}
+
+func TestParseLenient(t *testing.T) {
+	opts := ParseOptions{Lenient: true}
+	got, err := opts.Parse([]byte(`ret=OK,,garbage, a = 1 ,=x,name="open`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"ret": "OK", "a": "1", "name": "open"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// quoteFields encodes the fields with every field quoted.
+func quoteFields(fields []Field) []byte {
+	var s []string
+	for _, f := range fields {
+		s = append(s, `"`+strings.ReplaceAll(f.Key+"="+f.Value, `"`, `""`)+`"`)
+	}
+	return []byte(strings.Join(s, ","))
+}
+
+// tokenize returns all fields of body or the error.
+func tokenize(body []byte, opts ParseOptions) ([]Field, error) {
+	var fields []Field
+	tok := NewTokenizer(body, opts)
+	for {
+		f, err := tok.Next()
+		if err == io.EOF {
+			return fields, nil
+		}
+		if err != nil {
+			return fields, err
+		}
+		fields = append(fields, f)
+	}
+}
+
+// The seed corpus in testdata/fuzz/FuzzTokenizer contains replies of
+// real adapters.
+func FuzzTokenizer(f *testing.F) {
+	f.Add([]byte("ret=OK,pow=1,mode=3,stemp=22.0"))
+	f.Add([]byte(`ret=OK,name="a,""b""",x=1` + "\r\n"))
+	f.Add([]byte("ret=OK,,a\n=1,\"x"))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		for _, lenient := range []bool{false, true} {
+			opts := DefaultParseOptions
+			opts.Lenient = lenient
+			fields, err := tokenize(body, opts)
+			if err != nil {
+				var se *SyntaxError
+				if !errors.As(err, &se) {
+					t.Fatalf("lenient=%t: error %v is no SyntaxError", lenient, err)
+				}
+				if se.Offset < 0 || se.Offset > len(body) || se.Line < 1 || se.Column < 1 {
+					t.Fatalf("lenient=%t: invalid position %+v", lenient, se)
+				}
+				if lenient && !strings.Contains(se.Msg, "exceeds") {
+					t.Fatalf("lenient mode failed: %v", err)
+				}
+				continue
+			}
+			offset := -1
+			for _, fl := range fields {
+				if len(fl.Key) == 0 {
+					t.Fatalf("lenient=%t: empty key at %d", lenient, fl.Offset)
+				}
+				if fl.Offset <= offset || fl.Offset >= len(body) {
+					t.Fatalf("lenient=%t: invalid offset %d", lenient, fl.Offset)
+				}
+				offset = fl.Offset
+			}
+
+			// the fields parse the same if encoded again
+			again, err := tokenize(quoteFields(fields), ParseOptions{})
+			if err != nil {
+				t.Fatalf("lenient=%t: reparsing %q: %v", lenient, quoteFields(fields), err)
+			}
+			if len(again) != len(fields) {
+				t.Fatalf("lenient=%t: reparsing returned %d fields, want %d", lenient, len(again), len(fields))
+			}
+			for i := range fields {
+				if again[i].Key != fields[i].Key || again[i].Value != fields[i].Value {
+					t.Fatalf("lenient=%t: reparsing returned %q=%q, want %q=%q", lenient,
+						again[i].Key, again[i].Value, fields[i].Key, fields[i].Value)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package daikin
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// TemperatureUnit is the unit to display and enter temperatures in. The
+// units always use Celsius.
+type TemperatureUnit int
+
+const (
+	Celsius TemperatureUnit = iota
+	Fahrenheit
+)
+
+// ParseTemperatureUnit parses "C", "F", "celsius" or "fahrenheit".
+func ParseTemperatureUnit(s string) (TemperatureUnit, error) {
+	switch strings.ToLower(strings.TrimPrefix(s, "°")) {
+	case "", "c", "celsius":
+		return Celsius, nil
+	case "f", "fahrenheit":
+		return Fahrenheit, nil
+	}
+	return Celsius, fmt.Errorf("unknown temperature unit: %s", s)
+}
+
+func (u TemperatureUnit) String() string {
+	if u == Fahrenheit {
+		return "°F"
+	}
+	return "°C"
+}
+
+// Symbol returns "C" or "F".
+func (u TemperatureUnit) Symbol() string {
+	if u == Fahrenheit {
+		return "F"
+	}
+	return "C"
+}
+
+// toCelsius converts v in unit u to Celsius.
+func (u TemperatureUnit) toCelsius(v float64) float64 {
+	if u == Fahrenheit {
+		return (v - 32) * 5 / 9
+	}
+	return v
+}
+
+// fromCelsius converts v in Celsius to unit u.
+func (u TemperatureUnit) fromCelsius(v float64) float64 {
+	if u == Fahrenheit {
+		return v*9/5 + 32
+	}
+	return v
+}
+
+// roundHalf rounds v to the 0.5° resolution of the units.
+func roundHalf(v float64) float64 {
+	return math.Round(v*2) / 2
+}
@@ -1,6 +1,7 @@
 package daikin
 
 import (
+	"math"
         "strings"
 )
 
@@ -14,8 +15,14 @@ func (v *Version) String() string {
 	return v.value
 }
 
-func (v *Version) setUrlValues() string {
-	return v.param + "=" + v.String()
+// encode returns the version as reported by the unit.
+func (v *Version) encode() string {
+	return strings.Replace(v.value, ".", "_", -1)
+}
+
+// Float64 returns NaN, versions are not numeric.
+func (v *Version) Float64() float64 {
+	return math.NaN()
 }
 
 func (v *Version) decode(param string, s string) error {
@@ -25,3 +32,13 @@ func (v *Version) decode(param string, s string) error {
 	return nil
 }
 
+// MarshalText returns the value.
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.value), nil
+}
+
+// UnmarshalText sets the value.
+func (v *Version) UnmarshalText(text []byte) error {
+	v.value = string(text)
+	return nil
+}
@@ -0,0 +1,102 @@
+package daikin
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Event is sent by Watch if the state of a unit changed, e.g. with the
+// IR remote, or could not be fetched.
+type Event struct {
+	// Target is the address of the unit.
+	Target string
+	// Time is when the poll finished.
+	Time time.Time
+	// Changes are the values changed since the previous poll.
+	Changes []FieldChange
+	// State is the state fetched by the poll.
+	State State
+	// Err is the error returned by Refresh.
+	Err error
+}
+
+// WatchSections are the sections Watch compares by default, the
+// settings of the unit. The sensor readings and energy counters change
+// on almost every poll, they are only watched if passed explicitly.
+var WatchSections = []Section{SectionControlInfo, SectionZones}
+
+// Watch polls the unit every interval and sends an event for every
+// poll with changes of the sections or an error. Without sections,
+// WatchSections are compared. The first poll sets the baseline, it only
+// sends an event on error. The channel is closed when ctx is done.
+func (d *Daikin) Watch(ctx context.Context, interval time.Duration, sections ...Section) <-chan Event {
+	ch := make(chan Event)
+	go func() {
+		defer close(ch)
+		watch(ctx, d, interval, sections, ch)
+	}()
+	return ch
+}
+
+// Watch polls all devices every interval like Daikin.Watch and sends
+// the events of all devices on one channel. The devices are polled
+// concurrently. The channel is closed when ctx is done.
+func (d *DaikinNetwork) Watch(ctx context.Context, interval time.Duration, sections ...Section) <-chan Event {
+	ch := make(chan Event)
+	var wg sync.WaitGroup
+	for _, dev := range d.List() {
+		wg.Add(1)
+		go func(dev Device) {
+			defer wg.Done()
+			watch(ctx, dev, interval, sections, ch)
+		}(dev)
+	}
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+	return ch
+}
+
+// watch polls dev until ctx is done and sends the events with changes
+// of the sections to ch.
+func watch(ctx context.Context, dev Device, interval time.Duration, sections []Section, ch chan<- Event) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	if len(sections) == 0 {
+		sections = WatchSections
+	}
+
+	var prev *State
+	for {
+		st, err := dev.Refresh(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		ev := Event{Target: dev.Target(), Time: time.Now(), State: st, Err: err}
+		if prev != nil {
+			for _, c := range Diff(prev.Snapshot, st.Snapshot) {
+				if slices.Contains(sections, c.Section) {
+					ev.Changes = append(ev.Changes, c)
+				}
+			}
+		}
+		prev = &st
+		if len(ev.Changes) > 0 || ev.Err != nil {
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package daikin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DefaultAPAddress is the address of a factory-reset adapter in
+// access point mode.
+const DefaultAPAddress = "192.168.127.1"
+
+// WifiSecurity is the security mode of the Wifi network.
+type WifiSecurity string
+
+// Security modes supported by the Wifi adapter.
+const (
+	WifiSecurityNone  WifiSecurity = "none"
+	WifiSecurityWEP   WifiSecurity = "wep"
+	WifiSecurityMixed WifiSecurity = "mixed"
+	WifiSecurityWPA2  WifiSecurity = "wpa2"
+)
+
+var wifiSecurityMap = map[WifiSecurity]string{
+	WifiSecurityNone:  "None",
+	WifiSecurityWEP:   "WEP",
+	WifiSecurityMixed: "WPA/WPA2",
+	WifiSecurityWPA2:  "WPA2",
+}
+
+func (w *WifiSecurity) encode() string {
+	return string(*w)
+}
+
+func (w *WifiSecurity) decode(param string, s string) error {
+	return w.Decode(s)
+}
+
+// Float64 returns NaN, the security mode is not numeric.
+func (w *WifiSecurity) Float64() float64 {
+	return math.NaN()
+}
+
+func (w *WifiSecurity) Decode(s string) error {
+	// check if value is supported
+	if _, ok := wifiSecurityMap[WifiSecurity(s)]; !ok {
+		return fmt.Errorf("unknown security value: %s", s)
+	}
+	*w = WifiSecurity(s)
+	return nil
+}
+
+func (w *WifiSecurity) String() string {
+	v, ok := wifiSecurityMap[*w]
+	if !ok {
+		return fmt.Sprintf("Unknown WifiSecurity [%s]", string(*w))
+	}
+	return v
+}
+
+// WifiSetting represents the Wifi client configuration of the adapter.
+type WifiSetting struct {
+	// SSID is the name of the Wifi network to join.
+	SSID Name `daikin:"ssid"`
+	// Security is the security mode of the Wifi network.
+	Security WifiSecurity `daikin:"security"`
+	// Key is the pre-shared key of the Wifi network, it is never
+	// marshaled.
+	Key Name `daikin:"key" json:"-" yaml:"-"`
+	// Link is true if the adapter is connected to the Wifi network.
+	Link bool `daikin:"link"`
+}
+
+// ret=OK,ssid=%4d%79%4e%65%74,security=mixed,key=%73%65%63%72%65%74,link=1
+func (w *WifiSetting) populate(values map[string]string) error {
+	return decodeValues(w, values)
+}
+
+// urlValues returns the query string for /common/set_wifi_setting. The
+// adapter expects every byte of ssid and key percent-encoded.
+func (w *WifiSetting) urlValues() string {
+	values := "ssid=" + encodeWifiValue(w.SSID.String())
+	values = values + "&security=" + w.Security.encode()
+	values = values + "&key=" + encodeWifiValue(w.Key.String())
+	return values
+}
+
+func (w *WifiSetting) String() string {
+	link := "Disconnected"
+	if w.Link {
+		link = "Connected"
+	}
+	return fmt.Sprintf("SSID: %s\nSecurity: %s\nLink: %s",
+		w.SSID.String(), w.Security.String(), link)
+}
+
+// NewWifiSetting returns the Wifi configuration for ssid, security and key.
+func NewWifiSetting(ssid string, security WifiSecurity, key string) (*WifiSetting, error) {
+	if len(ssid) == 0 || len(ssid) > 32 {
+		return nil, fmt.Errorf("invalid ssid length: %d", len(ssid))
+	}
+	if _, ok := wifiSecurityMap[security]; !ok {
+		return nil, fmt.Errorf("unknown security value: %s", string(security))
+	}
+	if security != WifiSecurityNone && len(key) == 0 {
+		return nil, fmt.Errorf("security %s requires a key", string(security))
+	}
+	return &WifiSetting{
+		SSID:     Name{value: ssid, param: "ssid"},
+		Security: security,
+		Key:      Name{value: key, param: "key"},
+	}, nil
+}
+
+func encodeWifiValue(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		fmt.Fprintf(&b, "%%%02x", s[i])
+	}
+	return b.String()
+}
+
+// GetWifiSetting gets the Wifi client configuration of the adapter.
+func (d *Daikin) GetWifiSetting() error {
+	d.WifiSetting = &WifiSetting{}
+	vals, err := d.get(context.Background(), uriGetWifiSetting, "")
+	if err != nil {
+		return err
+	}
+	return d.WifiSetting.populate(vals)
+}
+
+// SetWifiSetting writes the Wifi client configuration to the adapter
+// and reads it back to verify that it was accepted. The new setting
+// becomes active after Reboot.
+func (d *Daikin) SetWifiSetting(w *WifiSetting) error {
+	if err := d.set(context.Background(), uriSetWifiSetting, w.urlValues()); err != nil {
+		return err
+	}
+	if err := d.GetWifiSetting(); err != nil {
+		return err
+	}
+	if d.WifiSetting.SSID.String() != w.SSID.String() {
+		return fmt.Errorf("adapter reports ssid %q, expected %q",
+			d.WifiSetting.SSID.String(), w.SSID.String())
+	}
+	if d.WifiSetting.Security != w.Security {
+		return fmt.Errorf("adapter reports security %s, expected %s",
+			string(d.WifiSetting.Security), string(w.Security))
+	}
+	return nil
+}
+
+// Reboot restarts the Wifi adapter.
+func (d *Daikin) Reboot() error {
+	return d.set(context.Background(), uriReboot, "")
+}
@@ -0,0 +1,101 @@
+package daikin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+func TestNewWifiSetting(t *testing.T) {
+	tests := []struct {
+		ssid     string
+		security daikin.WifiSecurity
+		key      string
+		ok       bool
+	}{
+		{"MyNet", daikin.WifiSecurityWPA2, "secret", true},
+		{"MyNet", daikin.WifiSecurityNone, "", true},
+		{"", daikin.WifiSecurityWPA2, "secret", false},
+		{strings.Repeat("x", 33), daikin.WifiSecurityWPA2, "secret", false},
+		{"MyNet", daikin.WifiSecurity("wpa3"), "secret", false},
+		{"MyNet", daikin.WifiSecurityMixed, "", false},
+	}
+	for _, tt := range tests {
+		_, err := daikin.NewWifiSetting(tt.ssid, tt.security, tt.key)
+		if (err == nil) != tt.ok {
+			t.Errorf("%q %s: got %v", tt.ssid, tt.security, err)
+		}
+	}
+}
+
+func TestSetWifiSetting(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+
+	w, err := daikin.NewWifiSetting("My Net&1", daikin.WifiSecurityWPA2, "se=cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newDaikin(s.Address())
+	if err := d.SetWifiSetting(w); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.WifiSetting.SSID.String(); got != "My Net&1" {
+		t.Errorf("ssid %q", got)
+	}
+	if got := d.WifiSetting.Key.String(); got != "se=cret" {
+		t.Errorf("key %q", got)
+	}
+	// every byte of ssid and key is percent-encoded
+	want := "/common/set_wifi_setting?ssid=%4d%79%20%4e%65%74%26%31&security=wpa2&key=%73%65%3d%63%72%65%74"
+	found := false
+	for _, r := range s.Requests() {
+		found = found || r == want
+	}
+	if !found {
+		t.Errorf("requests %v, want %s", s.Requests(), want)
+	}
+
+	out, err := json.Marshal(d.WifiSetting)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "cret") {
+		t.Errorf("key marshaled: %s", out)
+	}
+
+	if err := d.Reboot(); err != nil {
+		t.Fatal(err)
+	}
+	if r := s.Requests(); r[len(r)-1] != "/common/reboot" {
+		t.Errorf("last request %s", r[len(r)-1])
+	}
+}
+
+func TestSetWifiSettingIgnored(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+	// an adapter which accepts the setting, but keeps the old one
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/common/set_wifi_setting" {
+			w.Write([]byte("ret=OK"))
+			return
+		}
+		s.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	w, err := daikin.NewWifiSetting("MyNet", daikin.WifiSecurityWPA2, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newDaikin(strings.TrimPrefix(ts.URL, "http://"))
+	if err := d.SetWifiSetting(w); err == nil || !strings.Contains(err.Error(), "adapter reports ssid") {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package daikin
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Zone is a zone of a ducted AirBase system.
+type Zone struct {
+	// Name is the human-readable name of the zone.
+	Name string
+	// Power is the damper state of the zone (off/on).
+	Power Power
+}
+
+// Zones represents the zone settings of a ducted AirBase system.
+type Zones struct {
+	Zones []Zone
+	// zone_name as received, written back verbatim
+	rawNames string
+}
+
+// ret=OK,zone_name=%20%20%20Zone%201%3b%20%20%20Zone%202,zone_onoff=1%3b0
+func (z *Zones) populate(values map[string]string) error {
+	var names, states []string
+	for k, v := range values {
+		var err error
+		switch k {
+		case "zone_name":
+			z.rawNames = v
+			names, err = splitZoneValue(v)
+		case "zone_onoff":
+			states, err = splitZoneValue(v)
+		case "ret":
+			if v != returnOk {
+				err = fmt.Errorf("device returned error ret=%s", v)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(names) != len(states) {
+		return fmt.Errorf("got %d zone names but %d zone states", len(names), len(states))
+	}
+	z.Zones = make([]Zone, len(names))
+	for i := range names {
+		z.Zones[i].Name = strings.TrimSpace(names[i])
+		if err := z.Zones[i].Power.decode("zone_onoff", states[i]); err != nil {
+			return fmt.Errorf("zone %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+func splitZoneValue(v string) ([]string, error) {
+	s, err := url.PathUnescape(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(s) == 0 {
+		return nil, nil
+	}
+	return strings.Split(s, ";"), nil
+}
+
+func (z *Zones) urlValues() string {
+	states := make([]string, len(z.Zones))
+	for i := range z.Zones {
+		states[i] = strconv.Itoa(int(z.Zones[i].Power))
+	}
+	values := "zone_name=" + z.rawNames
+	values = values + "&zone_onoff=" + strings.Join(states, "%3b")
+	return values
+}
+
+// Find returns the zone with the given name or 1-based number.
+func (z *Zones) Find(zone string) (*Zone, error) {
+	for i := range z.Zones {
+		if strings.EqualFold(z.Zones[i].Name, zone) {
+			return &z.Zones[i], nil
+		}
+	}
+	if n, err := strconv.Atoi(zone); err == nil && n >= 1 && n <= len(z.Zones) {
+		return &z.Zones[n-1], nil
+	}
+	return nil, fmt.Errorf("unknown zone: %s", zone)
+}
+
+func (z *Zones) String() string {
+	var ret []string
+	for i := range z.Zones {
+		ret = append(ret, fmt.Sprintf("Zone %d (%s): %s",
+			i+1, z.Zones[i].Name, z.Zones[i].Power.String()))
+	}
+	return strings.Join(ret, "\n")
+}
+
+// GetZones gets the zone settings of a ducted AirBase system. Other
+// units return ErrNotSupported.
+func (d *Daikin) GetZones() error {
+	return d.getZones(context.Background())
+}
+
+func (d *Daikin) getZones(ctx context.Context) error {
+	if d.Protocol != ProtocolAirBase {
+		return fmt.Errorf("%s: %w", uriGetZoneSetting, ErrNotSupported)
+	}
+	if d.cached(SectionZones) {
+		return nil
+	}
+	vals, err := d.fetch(ctx, uriGetZoneSetting)
+	if err != nil {
+		return err
+	}
+	z := &Zones{}
+	if err := z.populate(vals); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.Zones = z
+	d.mu.Unlock()
+	d.fetched(SectionZones)
+	return nil
+}
+
+// SetZones configures the current zone settings to the unit.
+func (d *Daikin) SetZones() error {
+	if d.Zones == nil {
+		return fmt.Errorf("no zone settings, call GetZones first")
+	}
+	return d.SetZoneStates(context.Background(), d.Zones)
+}
@@ -0,0 +1,82 @@
+package daikin_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+func TestSetZoneStates(t *testing.T) {
+	const path = "/skyfi/aircon/get_zone_setting"
+	s := daikintest.NewAirBaseServer()
+	defer s.Close()
+	names := s.Get(path, "zone_name")
+
+	d := newDaikin(s.Address())
+	ctx := context.Background()
+	st, err := d.Refresh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := st.Zones
+	for _, tt := range []struct {
+		zone  string
+		power daikin.Power
+	}{
+		{"zone 3", daikin.PowerOn},
+		{"8", daikin.PowerOn},
+		{"Zone 1", daikin.PowerOff},
+	} {
+		zone, err := z.Find(tt.zone)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.zone, err)
+		}
+		zone.Power = tt.power
+	}
+	if err := d.SetZoneStates(ctx, z); err != nil {
+		t.Fatal(err)
+	}
+
+	var query string
+	for _, r := range s.Requests() {
+		if p, q, _ := strings.Cut(r, "?"); p == "/skyfi/aircon/set_zone_setting" {
+			query = q
+		}
+	}
+	want := "zone_name=" + names + "&zone_onoff=0%3b1%3b1%3b0%3b0%3b0%3b0%3b1"
+	if !strings.HasPrefix(query, want) {
+		t.Errorf("got query %q, want %q", query, want)
+	}
+	if got := s.Get(path, "zone_name"); got != names {
+		t.Errorf("zone names changed to %q", got)
+	}
+
+	st, err = d.Refresh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []daikin.Power{daikin.PowerOff, daikin.PowerOn, daikin.PowerOn, daikin.PowerOff,
+		daikin.PowerOff, daikin.PowerOff, daikin.PowerOff, daikin.PowerOn} {
+		if zone := st.Zones.Zones[i]; zone.Power != want || zone.Name != "Zone "+string(rune('1'+i)) {
+			t.Errorf("zone %d: got %+v", i+1, zone)
+		}
+	}
+}
+
+func TestFindUnknownZone(t *testing.T) {
+	s := daikintest.NewAirBaseServer()
+	defer s.Close()
+
+	st, err := newDaikin(s.Address()).Refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, zone := range []string{"Zone 9", "0", "9", ""} {
+		if _, err := st.Zones.Find(zone); err == nil || !strings.Contains(err.Error(), "unknown zone") {
+			t.Errorf("%q: got %v", zone, err)
+		}
+	}
+}
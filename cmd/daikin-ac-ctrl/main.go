@@ -15,29 +15,36 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io/fs"
-        "io/ioutil"
+	"math"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"syscall"
-
-	"gopkg.in/yaml.v3"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/config"
 	log "github.com/thkukuk/mqtt-exporter/pkg/logger"
 )
 
-type ConfigType struct {
-	Address string `yaml:"address,omitempty"`
-}
-
 const (
         CmdDevStatus int = 1
 	CmdPowerOn int = 2
 	CmdPowerOff int = 3
+	CmdSyncClock int = 4
+	CmdZones int = 5
+	CmdRaw int = 6
+	CmdCapture int = 7
+	CmdInventory int = 8
+	CmdSet int = 9
 )
 
 var (
@@ -46,11 +53,38 @@ var (
         Verbose = false
 	configFile = "config.yaml"
 	address string
+	selection = "all"
+	unitName string
+	unit daikin.TemperatureUnit
+	// Status
+	jsonOutput bool
 	// Power On
 	newTemperature string
 	newMode string
 	newFan string
-	
+	// Set
+	newPower string
+	newHumidity string
+	newFanDir string
+	newSwingUD string
+	newSwingLR string
+	// Sync Clock
+	newZone string
+	// Wifi Setup
+	wifiSSID string
+	wifiSecurity string
+	wifiKey string
+	wifiReboot bool
+	// Zones
+	zoneArgs []string
+	// Raw
+	rawRequestArgs []string
+	// Capture
+	captureDir string
+	// Register
+	regKey string
+	regPassword string
+
 	// daikinAcCtrlCmd represents the daikin-ac-ctrl command
 	daikinAcCtrlCmd = &cobra.Command {
 		Use:   "daikin-ac-ctrl",
@@ -64,7 +98,9 @@ func init() {
         daikinAcCtrlCmd.Version = Version
 
 	daikinAcCtrlCmd.PersistentFlags().StringVarP(&address, "address", "a", "", "Daikin aircon address")
+	daikinAcCtrlCmd.PersistentFlags().StringVarP(&selection, "select", "s", selection, "Devices to act on: all, name:<name>, mac:<mac>, group:<group> or addr:<address>, comma separated")
 	daikinAcCtrlCmd.PersistentFlags().StringVarP(&configFile, "config", "c", configFile, "configuration file")
+	daikinAcCtrlCmd.PersistentFlags().StringVar(&unitName, "unit", "", "display unit of temperatures (C or F)")
 
 	daikinAcCtrlCmd.PersistentFlags().BoolVarP(&Quiet, "quiet", "q", Quiet, "don't print any informative messages")
 	daikinAcCtrlCmd.PersistentFlags().BoolVarP(&Verbose, "verbose", "v", Verbose, "become really verbose in printing messages")
@@ -73,6 +109,14 @@ func init() {
 	        DevStatusCmd(),
 		PowerOnCmd(),
 		PowerOffCmd(),
+		SetCmd(),
+		SyncClockCmd(),
+		WifiSetupCmd(),
+		ZonesCmd(),
+		RawCmd(),
+		CaptureCmd(),
+		RegisterCmd(),
+		InventoryCmd(),
 	)
 }
 
@@ -84,6 +128,8 @@ func DevStatusCmd() *cobra.Command {
                 Args:  cobra.ExactArgs(0),
         }
 
+	subCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Print the status as JSON")
+
         return subCmd
 }
 
@@ -95,7 +141,7 @@ func PowerOnCmd() *cobra.Command {
                 Args:  cobra.ExactArgs(0),
         }
 
-	subCmd.PersistentFlags().StringVarP(&newTemperature, "temperature", "t", "", "Target temperature")
+	subCmd.PersistentFlags().StringVarP(&newTemperature, "temperature", "t", "", "Target temperature in the display unit")
 	subCmd.PersistentFlags().StringVarP(&newMode, "mode", "m", "", "Operating mode (0=Auto, 2=Dehumidify, 3=Cool, 4=Heat, 6=Fan)")
 	subCmd.PersistentFlags().StringVarP(&newFan, "fan", "f", "", "Fan speed (A=Auto, B=Silent, 3=Fan1, 4=Fan2, 5=Fan3, 6=Fan4, 7=Fan5)")
 
@@ -113,24 +159,224 @@ func PowerOffCmd() *cobra.Command {
         return subCmd
 }
 
-func read_yaml_config(conffile string) (ConfigType, error) {
+func SetCmd() *cobra.Command {
+        var subCmd = &cobra.Command {
+                Use:   "set",
+                Short: "Change settings of daikin aircon without switching it on",
+                Long:  "Change the given settings and keep all others, including the power state.",
+                Run:   set,
+                Args:  setArgs,
+        }
+
+	subCmd.PersistentFlags().StringVarP(&newPower, "power", "p", "", "Power (on, off)")
+	subCmd.PersistentFlags().StringVarP(&newMode, "mode", "m", "", "Operating mode (auto, dehumidify, cool, heat, fan or 0, 2, 3, 4, 6)")
+	subCmd.PersistentFlags().StringVarP(&newTemperature, "temperature", "t", "", "Target temperature in the display unit")
+	subCmd.PersistentFlags().StringVar(&newHumidity, "humidity", "", "Target humidity in percent")
+	subCmd.PersistentFlags().StringVarP(&newFan, "fan", "f", "", "Fan speed (auto, silent, level1-level5 or A, B, 3-7)")
+	subCmd.PersistentFlags().StringVarP(&newFanDir, "fan-dir", "d", "", "Louvre swing (stopped, vertical, horizontal, both)")
+	subCmd.PersistentFlags().StringVar(&newSwingUD, "swing-ud", "", "Vertical louvre swing (stopped, swing)")
+	subCmd.PersistentFlags().StringVar(&newSwingLR, "swing-lr", "", "Horizontal louvre swing (stopped, swing)")
+
+        return subCmd
+}
 
-        var config ConfigType
+func setArgs(cmd *cobra.Command, args []string) error {
+	if err := cobra.ExactArgs(0)(cmd, args); err != nil {
+		return err
+	}
+	if len(newPower + newMode + newTemperature + newHumidity + newFan + newFanDir + newSwingUD + newSwingLR) == 0 {
+		return fmt.Errorf("no setting to change given")
+	}
+	if len(newFanDir) > 0 && (len(newSwingUD) > 0 || len(newSwingLR) > 0) {
+		return fmt.Errorf("--fan-dir cannot be combined with --swing-ud or --swing-lr")
+	}
+	return nil
+}
 
-        file, err := ioutil.ReadFile(conffile)
-        if err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
-			return config, nil
-		} else {
-			return config, fmt.Errorf("Cannot read %q: %v", conffile, err)
+func SyncClockCmd() *cobra.Command {
+        var subCmd = &cobra.Command {
+                Use:   "sync-clock",
+                Short: "Set clock of daikin aircon to the local time",
+                Run:   syncClock,
+                Args:  cobra.ExactArgs(0),
+        }
+
+	subCmd.PersistentFlags().StringVarP(&newZone, "zone", "z", "", "Time zone index to configure on the adapter, e.g. 54")
+
+        return subCmd
+}
+
+func WifiSetupCmd() *cobra.Command {
+        var subCmd = &cobra.Command {
+                Use:   "wifi-setup",
+                Short: "Join a daikin aircon in access point mode to a Wifi network",
+                Long:  `Configures the Wifi network of an adapter in access point mode.
+Without --ssid, the current configuration is printed. If no address is
+given, ` + daikin.DefaultAPAddress + ` is used.`,
+                Run:   wifiSetup,
+                Args:  cobra.ExactArgs(0),
+        }
+
+	subCmd.PersistentFlags().StringVar(&wifiSSID, "ssid", "", "SSID of the Wifi network")
+	subCmd.PersistentFlags().StringVar(&wifiSecurity, "security", string(daikin.WifiSecurityMixed), "Security mode (none, wep, mixed, wpa2)")
+	subCmd.PersistentFlags().StringVar(&wifiKey, "key", "", "Key of the Wifi network")
+	subCmd.PersistentFlags().BoolVar(&wifiReboot, "reboot", true, "Reboot the adapter to activate the new setting")
+
+        return subCmd
+}
+
+func ZonesCmd() *cobra.Command {
+        var subCmd = &cobra.Command {
+                Use:   "zones [on|off <zone>...]",
+                Short: "Show or switch zones of ducted daikin aircon",
+                Long:  `Without arguments, the zones and their state are printed.
+With "on" or "off", the given zones are opened or closed. Zones
+can be specified by name or number.`,
+                Run:   zones,
+                Args:  zonesArgs,
+        }
+
+        return subCmd
+}
+
+func zonesArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	if args[0] != "on" && args[0] != "off" {
+		return fmt.Errorf("unknown action %q, expected on or off", args[0])
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("%s requires at least one zone", args[0])
+	}
+	return nil
+}
+
+func RawCmd() *cobra.Command {
+        var subCmd = &cobra.Command {
+                Use:   "raw get <path> | raw set <path> [key=value...]",
+                Short: "Query or set an endpoint of a daikin aircon directly",
+                Long:  `Sends a request to the given path, e.g. /aircon/get_timer,
+and prints the key/value pairs of the reply. The path is sent
+as is, so it has to match the protocol of the adapter. Values
+of "set" are sent verbatim and have to be percent-encoded.`,
+                Run:   raw,
+                Args:  rawArgs,
+        }
+
+        return subCmd
+}
+
+func rawArgs(cmd *cobra.Command, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("requires an action (get or set) and a path")
+	}
+	switch args[0] {
+	case "get":
+		if len(args) > 2 {
+			return fmt.Errorf("get accepts no parameters")
+		}
+	case "set":
+		for _, kv := range args[2:] {
+			if k, _, ok := strings.Cut(kv, "="); !ok || len(k) == 0 {
+				return fmt.Errorf("invalid parameter %q, expected key=value", kv)
+			}
 		}
+	default:
+		return fmt.Errorf("unknown action %q, expected get or set", args[0])
+	}
+	if !strings.HasPrefix(args[1], "/") {
+		return fmt.Errorf("path %q must start with /", args[1])
+	}
+	return nil
+}
+
+func CaptureCmd() *cobra.Command {
+        var subCmd = &cobra.Command {
+                Use:   "capture",
+                Short: "Record the responses of daikin aircon for bug reports",
+                Long:  `Queries the state of the devices and writes all requests and
+responses to one fixture file per device. MAC addresses, SSIDs,
+keys and passwords are redacted, so that the files can be attached
+to issues.`,
+                Run:   capture,
+                Args:  cobra.ExactArgs(0),
         }
-        err = yaml.Unmarshal(file, &config)
-        if err != nil {
-                return config, fmt.Errorf("Unmarshal error: %v", err)
+
+	subCmd.PersistentFlags().StringVarP(&captureDir, "output", "o", ".", "Directory to write the fixture files to")
+
+        return subCmd
+}
+
+func RegisterCmd() *cobra.Command {
+        var subCmd = &cobra.Command {
+                Use:   "register",
+                Short: "Register as terminal with a daikin aircon requiring HTTPS",
+                Long:  `Registers with the key printed on a BRP072C adapter and stores
+the credentials for the address in the configuration file.`,
+                Run:   register,
+                Args:  cobra.ExactArgs(0),
         }
 
-        return config, nil
+	subCmd.PersistentFlags().StringVarP(&regKey, "key", "k", "", "Key printed on the adapter")
+	subCmd.PersistentFlags().StringVarP(&regPassword, "password", "p", "", "Local password (lpw) of the adapter")
+	subCmd.MarkPersistentFlagRequired("key")
+
+        return subCmd
+}
+
+func InventoryCmd() *cobra.Command {
+        var subCmd = &cobra.Command {
+                Use:   "inventory [alias <unit> <alias> | groups <unit> [group...]]",
+                Short: "Show the inventory of daikin aircon or assign aliases and groups",
+                Long:  `Without arguments, the devices are discovered, recorded in the
+inventory file configured with "inventory" and the inventory is
+printed. "alias" and "groups" assign an alias or the groups to the
+unit with the given MAC address or alias. Aliases and MAC addresses
+can be used instead of addresses with --address and --select.`,
+                Run:   inventory,
+                Args:  inventoryArgs,
+        }
+
+        return subCmd
+}
+
+func inventoryArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	switch args[0] {
+	case "alias":
+		if len(args) != 3 {
+			return fmt.Errorf("alias requires a unit and an alias")
+		}
+	case "groups":
+		if len(args) < 2 {
+			return fmt.Errorf("groups requires a unit")
+		}
+	default:
+		return fmt.Errorf("unknown action %q, expected alias or groups", args[0])
+	}
+	return nil
+}
+
+// load_inventory loads the inventory file of the configuration, it
+// returns nil if none is configured.
+func load_inventory(conf *config.Config) (*daikin.Inventory, error) {
+	if len(conf.Inventory) == 0 {
+		return nil, nil
+	}
+	return daikin.LoadInventory(conf.Inventory)
+}
+
+// save_inventory writes the inventory, if there is one and it changed.
+func save_inventory(inv *daikin.Inventory) {
+	if inv == nil {
+		return
+	}
+	if err := inv.Save(); err != nil {
+		log.Errorf("Could not save inventory: %v", err)
+	}
 }
 
 func main() {
@@ -151,19 +397,165 @@ func powerOff(cmd *cobra.Command, args []string) {
         runDaikinAcCtrlCmd(CmdPowerOff)
 }
 
+func set(cmd *cobra.Command, args []string) {
+        runDaikinAcCtrlCmd(CmdSet)
+}
+
+func syncClock(cmd *cobra.Command, args []string) {
+        runDaikinAcCtrlCmd(CmdSyncClock)
+}
+
+func wifiSetup(cmd *cobra.Command, args []string) {
+	if len(address) == 0 {
+		address = daikin.DefaultAPAddress
+	}
+	d := &daikin.Daikin{Address: address}
+
+	if len(wifiSSID) == 0 {
+		if err := d.GetWifiSetting(); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		fmt.Printf("Current %s:\n%s\n", address, d.WifiSetting)
+		return
+	}
+
+	w, err := daikin.NewWifiSetting(wifiSSID, daikin.WifiSecurity(wifiSecurity), wifiKey)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	fmt.Printf("Configuring %s to join %q\n", address, wifiSSID)
+	if err := d.SetWifiSetting(w); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	fmt.Printf("%s\n", d.WifiSetting)
+	if wifiReboot {
+		fmt.Printf("Rebooting %s\n", address)
+		if err := d.Reboot(); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+}
+
+func zones(cmd *cobra.Command, args []string) {
+	zoneArgs = args
+        runDaikinAcCtrlCmd(CmdZones)
+}
+
+func raw(cmd *cobra.Command, args []string) {
+	rawRequestArgs = args
+        runDaikinAcCtrlCmd(CmdRaw)
+}
+
+func capture(cmd *cobra.Command, args []string) {
+        runDaikinAcCtrlCmd(CmdCapture)
+}
+
+func inventory(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		runDaikinAcCtrlCmd(CmdInventory)
+		return
+	}
+	conf, err := config.Load(configFile)
+	if err != nil {
+		log.Fatalf("Could not load config: %v", err)
+	}
+	inv, err := load_inventory(conf)
+	if err != nil {
+		log.Fatalf("Could not load inventory: %v", err)
+	}
+	if inv == nil {
+		log.Fatalf("Error: no inventory file configured in %q", configFile)
+	}
+	if args[0] == "alias" {
+		err = inv.SetAlias(args[1], args[2])
+	} else {
+		err = inv.SetGroups(args[1], args[2:])
+	}
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if err := inv.Save(); err != nil {
+		log.Fatalf("Could not save inventory: %v", err)
+	}
+	e, _ := inv.Lookup(args[1])
+	fmt.Printf("%s\n", &e)
+}
+
+func register(cmd *cobra.Command, args []string) {
+	conf, err := config.Load(configFile)
+	if err != nil {
+		log.Fatalf("Could not load config: %v", err)
+	}
+        if len(address) == 0 && len(conf.Address) > 0 {
+                address = conf.Address
+        }
+	if len(address) == 0 {
+		log.Fatal("Error: register requires an address")
+	}
+	inv, err := load_inventory(conf)
+	if err != nil {
+		log.Fatalf("Could not load inventory: %v", err)
+	}
+	// key is the address or MAC address the credentials are stored for
+	key := address
+	if dev := conf.Device(address); dev != nil {
+		key = dev.Key()
+	}
+	if inv != nil {
+		if e, ok := inv.Lookup(key); ok {
+			key = e.MAC
+		}
+		address = inv.Resolve(key)
+	} else {
+		address = key
+	}
+
+	d := &daikin.Daikin{Address: address}
+	d.Credentials = conf.CredentialsOf(key)
+	if len(regPassword) > 0 {
+		d.Credentials.Password = regPassword
+	}
+
+	fmt.Printf("Registering with %s\n", address)
+	if err := d.RegisterTerminal(context.Background(), regKey); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	cred := config.Credentials{
+		UUID:     d.Credentials.UUID,
+		Key:      d.Credentials.Key,
+		Password: d.Credentials.Password,
+		Secure:   d.Credentials.Secure,
+	}
+	if err := config.SaveCredentials(configFile, key, cred); err != nil {
+		log.Fatalf("Could not save credentials: %v", err)
+	}
+	if !Quiet {
+		log.Infof("Stored credentials for %s in %q\n", key, configFile)
+	}
+
+	if err := d.GetBasicInfo(); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	fmt.Printf("%s\n", d.BasicInfo)
+}
+
 func runDaikinAcCtrlCmd(cmd int) {
 
 	if !Quiet {
 		log.Infof("Read yaml config %q\n", configFile)
 	}
-	config, err := read_yaml_config(configFile)
+	conf, err := config.Load(configFile)
 	if err != nil {
 		log.Fatalf("Could not load config: %v", err)
 	}
 
-        if len(address) == 0 && len(config.Address) > 0 {
-                address = config.Address
-        }
+	if len(unitName) == 0 {
+		unitName = conf.Unit
+	}
+	unit, err = daikin.ParseTemperatureUnit(unitName)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
 
 	if !Quiet {
                 log.Infof("Daikin AC Ctrl %s\n", Version)
@@ -181,69 +573,424 @@ func runDaikinAcCtrlCmd(cmd int) {
                 os.Exit(0)
         }()
 
-	d, err := daikin.NewNetwork(daikin.DebugOption(Verbose),
-		daikin.AddressOption(address))
+	sel, err := daikin.ParseSelector(selection)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	// check the new settings before contacting any unit
+	var changes []daikin.Change
+	switch cmd {
+	case CmdPowerOn:
+		changes, err = powerOnChanges()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	case CmdPowerOff:
+		changes = []daikin.Change{daikin.WithPower(daikin.PowerOff)}
+	case CmdSet:
+		changes, err = setChanges()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	case CmdSyncClock:
+		if err := daikin.CheckClockZone(newZone); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+
+	inv, err := load_inventory(conf)
+	if err != nil {
+		log.Fatalf("Could not load inventory: %v", err)
+	}
+
+	opts := append(conf.NetworkOptions(address),
+		daikin.DebugOption(Verbose),
+		daikin.InventoryOption(inv))
+	d, err := daikin.NewNetwork(opts...)
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
         if err = d.Discover(); err != nil {
-		log.Fatalf("Discover Error: %v", err)
+		if len(d.Devices) == 0 {
+			log.Fatalf("Discover Error: %v", err)
+		}
+		log.Warnf("Discover Error: %v", err)
         }
+	save_inventory(inv)
 
-	for target, d := range d.Devices {
+	ctx := context.Background()
+
+	if cmd == CmdInventory {
+		if inv == nil {
+			log.Fatalf("Error: no inventory file configured in %q", configFile)
+		}
+		for _, e := range inv.Entries() {
+			fmt.Printf("%s\n\n", &e)
+		}
+		return
+	}
+
+	switch cmd {
+	case CmdPowerOn:
+		os.Exit(applyChanges(ctx, d, sel, "Switched %s on", changes))
+	case CmdPowerOff:
+		os.Exit(applyChanges(ctx, d, sel, "Switched %s off", changes))
+	case CmdSet:
+		os.Exit(applyChanges(ctx, d, sel, "Updated %s", changes))
+	}
+
+	devs, err := d.Select(ctx, sel)
+	if len(devs) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no device matches %q", sel.String())
+		}
+		log.Fatalf("Error: %v", err)
+	}
+	if err != nil {
+		log.Warn(err)
+	}
+	targets := make([]string, 0, len(devs))
+	for t := range devs {
+		targets = append(targets, t)
+	}
+	sort.Strings(targets)
+
+	var statuses []statusJSON
+	// a failing device does not stop the others, but the exit status
+	failed := false
+
+	for _, target := range targets {
+		dev := devs[target]
+
+		switch cmd {
+		case CmdCapture:
+			if err := captureDevice(ctx, target, dev); err != nil {
+				log.Error(err)
+				failed = true
+			}
+			continue
+		case CmdRaw:
+			if err := rawRequest(ctx, target, dev); err != nil {
+				log.Error(err)
+				failed = true
+			}
+			continue
+		}
+
+		state, err := dev.Refresh(ctx)
+		if err != nil {
+			log.Error(err)
+			failed = true
+			continue
+		}
+		if inv != nil && inv.Update(target, state.BasicInfo, state.ModelInfo) {
+			save_inventory(inv)
+		}
 
-                if err := d.GetBasicInfo(); err != nil {
-                        log.Error(err)
-                        continue
-                }
-		if err := d.GetControlInfo(); err != nil {
-                        log.Error(err)
-                        continue
-                }
-                if err := d.GetSensorInfo(); err != nil {
-                        log.Error(err)
-                        continue
-                }
-                if err := d.GetPowerInfo(); err != nil {
-                        log.Error(err)
-                        continue
-                }
-		
 		switch cmd {
     		case CmdDevStatus:
-			fmt.Printf("Current %s:\n%s\n", target, d)
-    		case CmdPowerOn:
-			fmt.Printf("Switching %s on\n", target)
-	             	d.ControlInfo.Power = daikin.PowerOn
-			if len(newTemperature) > 0 {
-			        if err := d.ControlInfo.Temperature.Set(newTemperature); err != nil {
-			       	      log.Error(err)
-				      os.Exit(1)
-				}
+			if jsonOutput {
+				statuses = append(statuses, newStatusJSON(target, &state.Snapshot, unit))
+				continue
 			}
-			if len(newMode) > 0 {
-			   	if err := d.ControlInfo.Mode.Decode(newMode); err != nil {
-			       	      log.Error(err)
-				      os.Exit(1)
+			fmt.Printf("Current %s:\n%s\n", target, state.Format(unit))
+		case CmdSyncClock:
+			now := time.Now()
+			if err := dev.SetClock(ctx, now, newZone); err != nil {
+				if errors.Is(err, daikin.ErrNotSupported) {
+					fmt.Printf("%s has no settable clock\n", target)
+					continue
 				}
+				log.Error(err)
+				failed = true
+				continue
+			}
+			fmt.Printf("Set clock of %s to %s\n", target, now.Format(time.DateTime))
+		case CmdZones:
+			if state.Zones == nil {
+				fmt.Printf("%s has no zones\n", target)
+				continue
 			}
-			if len(newFan) > 0 {
-			   	if err := d.ControlInfo.Fan.Decode(newFan); err != nil {
-			       	      log.Error(err)
-				      os.Exit(1)
+			if len(zoneArgs) > 0 {
+				power := daikin.PowerOff
+				if zoneArgs[0] == "on" {
+					power = daikin.PowerOn
+				}
+				if err := switchZones(ctx, dev, state.Zones, power); err != nil {
+					log.Errorf("%s: %v", target, err)
+					failed = true
+					continue
 				}
 			}
-     	 	     	if err := d.SetControlInfo(); err != nil {
-	       	     	       	log.Error(err)
-               			os.Exit(1)
-         		}
-		case CmdPowerOff:
-			fmt.Printf("Switching %s off\n", target)
-	             	d.ControlInfo.Power = daikin.PowerOff
-     	 	     	if err := d.SetControlInfo(); err != nil {
-	       	     	       	log.Error(err)
-               		        os.Exit(1)
-         		}
+			fmt.Printf("Zones of %s:\n%s\n", target, state.Zones)
     		}
 	}
+
+	if jsonOutput && cmd == CmdDevStatus {
+		out, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		fmt.Printf("%s\n", out)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// applyChanges applies the changes to the selected devices in parallel
+// and prints the result per device with format, which gets the target
+// and name of the device. It returns the exit status.
+func applyChanges(ctx context.Context, d *daikin.DaikinNetwork, sel daikin.Selector, format string, changes []daikin.Change) int {
+	report, err := d.Apply(ctx, sel, changes...)
+	if err != nil {
+		if report == nil {
+			log.Errorf("Error: %v", err)
+			return 1
+		}
+		log.Warn(err)
+	}
+	status := 0
+	for _, res := range report {
+		if res.Err != nil {
+			log.Error(res.String())
+			status = 1
+			continue
+		}
+		target := res.Target
+		if len(res.Name) > 0 {
+			target = fmt.Sprintf("%s (%s)", res.Target, res.Name)
+		}
+		fmt.Printf(format+"\n", target)
+	}
+	return status
+}
+
+// switchZones sets the zones named in zoneArgs to power and writes them.
+func switchZones(ctx context.Context, dev daikin.Device, z *daikin.Zones, power daikin.Power) error {
+	for _, name := range zoneArgs[1:] {
+		zone, err := z.Find(name)
+		if err != nil {
+			return err
+		}
+		zone.Power = power
+	}
+	return dev.SetZoneStates(ctx, z)
+}
+
+// rawRequest sends the request of the raw command to dev and prints
+// the reply sorted by key.
+func rawRequest(ctx context.Context, target string, dev daikin.Device) error {
+	rd, ok := dev.(interface {
+		GetRaw(context.Context, string) (map[string]string, error)
+		SetRaw(context.Context, string, map[string]string) (map[string]string, error)
+	})
+	if !ok {
+		return fmt.Errorf("%s: raw requests are not supported", target)
+	}
+
+	var vals map[string]string
+	var err error
+	if rawRequestArgs[0] == "get" {
+		vals, err = rd.GetRaw(ctx, rawRequestArgs[1])
+	} else {
+		params := map[string]string{}
+		for _, kv := range rawRequestArgs[2:] {
+			k, v, _ := strings.Cut(kv, "=")
+			params[k] = v
+		}
+		vals, err = rd.SetRaw(ctx, rawRequestArgs[1], params)
+	}
+	if err != nil && vals == nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Printf("%s %s:\n", target, rawRequestArgs[1])
+	for _, k := range keys {
+		fmt.Printf("  %s=%s\n", k, vals[k])
+	}
+	return err
+}
+
+// captureDevice records the state of dev and writes it as fixture to
+// captureDir.
+func captureDevice(ctx context.Context, target string, dev daikin.Device) error {
+	d, ok := dev.(*daikin.Daikin)
+	if !ok {
+		return fmt.Errorf("%s: capturing is not supported", target)
+	}
+	rec := &daikin.Recorder{}
+	if d.Client != nil {
+		rec.Transport = d.Client.Transport
+	}
+	d.Client = rec.Client()
+
+	if _, err := d.Refresh(ctx); err != nil {
+		return err
+	}
+	if err := d.GetDateTime(); err != nil && Verbose {
+		log.Debugf("%s: %v", target, err)
+	}
+
+	f := rec.Fixture()
+	f.Comment = fmt.Sprintf("%s adapter, firmware %s, captured by daikin-ac-ctrl %s",
+		d.Protocol.String(), d.BasicInfo.Version.String(), Version)
+	name := strings.NewReplacer(":", "_", "/", "_").Replace(target)
+	file := filepath.Join(captureDir, "daikin-"+name+".json")
+	if err := f.Save(file); err != nil {
+		return err
+	}
+	fmt.Printf("Captured %d requests to %s in %s\n", len(f.Exchanges), target, file)
+	return nil
+}
+
+// powerOnChanges returns the changes requested with the on command.
+func powerOnChanges() ([]daikin.Change, error) {
+	changes, err := modeChanges()
+	if err != nil {
+		return nil, err
+	}
+	return append([]daikin.Change{daikin.WithPower(daikin.PowerOn)}, changes...), nil
+}
+
+// modeChanges returns the changes of mode, target temperature and fan
+// speed shared by the on and set commands.
+func modeChanges() ([]daikin.Change, error) {
+	var changes []daikin.Change
+	var modes []daikin.Mode
+	if len(newMode) > 0 {
+		var m daikin.Mode
+		if err := m.UnmarshalText([]byte(newMode)); err != nil {
+			return nil, err
+		}
+		changes = append(changes, daikin.WithMode(m))
+		modes = append(modes, m)
+	}
+	if len(newTemperature) > 0 {
+		v, err := strconv.ParseFloat(newTemperature, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid temperature %q", newTemperature)
+		}
+		t := daikin.NewTemperature(v, unit)
+		if err := daikin.CheckTemperature(t.Value(), nil, modes...); err != nil {
+			if unit == daikin.Fahrenheit {
+				return nil, fmt.Errorf("%s°F is %s°C: %v", newTemperature, t.String(), err)
+			}
+			return nil, err
+		}
+		changes = append(changes, daikin.WithTemperature(t.Value()))
+	}
+	if len(newFan) > 0 {
+		var f daikin.Fan
+		if err := f.UnmarshalText([]byte(newFan)); err != nil {
+			return nil, err
+		}
+		changes = append(changes, daikin.WithFan(f))
+	}
+	return changes, nil
+}
+
+// setChanges returns the changes requested with the set command. The
+// power is only changed if --power is given.
+func setChanges() ([]daikin.Change, error) {
+	var changes []daikin.Change
+	if len(newPower) > 0 {
+		var p daikin.Power
+		if err := p.UnmarshalText([]byte(newPower)); err != nil {
+			return nil, err
+		}
+		changes = append(changes, daikin.WithPower(p))
+	}
+	mc, err := modeChanges()
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, mc...)
+	if len(newHumidity) > 0 {
+		h, err := strconv.Atoi(newHumidity)
+		if err != nil || h < 0 || h > 100 {
+			return nil, fmt.Errorf("invalid humidity %q, expected 0-100", newHumidity)
+		}
+		changes = append(changes, daikin.WithHumidity(h))
+	}
+	if len(newFanDir) > 0 {
+		var f daikin.FanDir
+		if err := f.UnmarshalText([]byte(newFanDir)); err != nil {
+			return nil, err
+		}
+		changes = append(changes, daikin.WithFanDir(f))
+	}
+	if len(newSwingUD) > 0 {
+		var ud daikin.Swing
+		if err := ud.UnmarshalText([]byte(newSwingUD)); err != nil {
+			return nil, err
+		}
+		changes = append(changes, daikin.WithVerticalSwing(ud))
+	}
+	if len(newSwingLR) > 0 {
+		var lr daikin.Swing
+		if err := lr.UnmarshalText([]byte(newSwingLR)); err != nil {
+			return nil, err
+		}
+		changes = append(changes, daikin.WithHorizontalSwing(lr))
+	}
+	return changes, nil
+}
+
+// statusJSON is the status of a unit printed by "status --json".
+// Temperatures are converted to the display unit.
+type statusJSON struct {
+	Target string `json:"target"`
+	Unit   string `json:"unit"`
+	daikin.Snapshot
+	ControlInfo *controlJSON `json:",omitempty"`
+	SensorInfo  *sensorJSON  `json:",omitempty"`
+}
+
+type controlJSON struct {
+	*daikin.ControlInfo
+	Temperature displayTemperature
+}
+
+type sensorJSON struct {
+	*daikin.SensorInfo
+	HomeTemperature    displayTemperature
+	OutsideTemperature displayTemperature
+}
+
+// displayTemperature marshals a temperature in the display unit,
+// Fahrenheit rounded to whole degrees like Temperature.Format.
+type displayTemperature struct {
+	t    daikin.Temperature
+	unit daikin.TemperatureUnit
+}
+
+func (d displayTemperature) MarshalJSON() ([]byte, error) {
+	if !d.t.IsSet() {
+		return d.t.MarshalJSON()
+	}
+	v := d.t.In(d.unit)
+	if d.unit == daikin.Fahrenheit {
+		v = math.Round(v)
+	}
+	return json.Marshal(v)
+}
+
+func newStatusJSON(target string, s *daikin.Snapshot, unit daikin.TemperatureUnit) statusJSON {
+	st := statusJSON{Target: target, Unit: unit.Symbol(), Snapshot: *s}
+	if c := s.ControlInfo; c != nil {
+		st.ControlInfo = &controlJSON{c, displayTemperature{c.Temperature, unit}}
+	}
+	if se := s.SensorInfo; se != nil {
+		st.SensorInfo = &sensorJSON{se,
+			displayTemperature{se.HomeTemperature, unit},
+			displayTemperature{se.OutsideTemperature, unit}}
+	}
+	return st
 }
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/api/daikintest"
+)
+
+// runSet runs the set command with the flags in args against the fake
+// adapter s and returns the exit status.
+func runSet(t *testing.T, s *daikintest.Server, args map[string]string) int {
+	t.Helper()
+	flags := map[string]*string{
+		"power": &newPower, "mode": &newMode, "temperature": &newTemperature,
+		"fan": &newFan, "humidity": &newHumidity, "fan-dir": &newFanDir,
+		"swing-ud": &newSwingUD, "swing-lr": &newSwingLR,
+	}
+	for name, v := range flags {
+		old := *v
+		*v = args[name]
+		t.Cleanup(func() { *v = old })
+	}
+
+	changes, err := setChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := daikin.NewNetwork(daikin.AddressOption(s.Address()),
+		daikin.PolicyOption(daikin.RequestPolicy{Retries: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	sel, err := daikin.ParseSelector("all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return applyChanges(context.Background(), d, sel, "Updated %s", changes)
+}
+
+func TestSet(t *testing.T) {
+	const path = "/aircon/get_control_info"
+	tests := []struct {
+		name    string
+		control map[string]string
+		args    map[string]string
+		want    map[string]string
+	}{
+		{"fan to cool", map[string]string{"mode": "6", "stemp": "--", "dt3": "24.0"},
+			map[string]string{"mode": "cool"},
+			map[string]string{"mode": "3", "stemp": "24.0"}},
+		{"heat to cool", map[string]string{"mode": "4", "stemp": "12.0"},
+			map[string]string{"mode": "cool"},
+			map[string]string{"mode": "3", "stemp": "18.0"}},
+		{"off at heat 31", map[string]string{"pow": "1", "mode": "4", "stemp": "31.0"},
+			map[string]string{"power": "off"},
+			map[string]string{"pow": "0", "stemp": "31.0"}},
+		{"dehumidify", map[string]string{"mode": "3", "stemp": "25.0"},
+			map[string]string{"mode": "dehumidify"},
+			map[string]string{"mode": "2", "stemp": "M", "shum": "AUTO"}},
+		{"temperature and fan", nil,
+			map[string]string{"temperature": "23.5", "fan": "level3"},
+			map[string]string{"stemp": "23.5", "f_rate": "5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := daikintest.NewServer()
+			defer s.Close()
+			for k, v := range tt.control {
+				s.Set(path, k, v)
+			}
+
+			if status := runSet(t, s, tt.args); status != 0 {
+				t.Fatalf("exit status %d", status)
+			}
+			for k, v := range tt.want {
+				if got := s.Get(path, k); got != v {
+					t.Errorf("%s is %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestSetRejected(t *testing.T) {
+	const path = "/aircon/get_control_info"
+	s := daikintest.NewServer()
+	defer s.Close()
+	s.Set(path, "mode", "6")
+	s.Set(path, "stemp", "--")
+
+	if status := runSet(t, s, map[string]string{"temperature": "22"}); status != 1 {
+		t.Errorf("exit status %d, want 1", status)
+	}
+	if got := s.Get(path, "stemp"); got != "--" {
+		t.Errorf("stemp is %q, want --", got)
+	}
+}
+
+func TestStatusJSON(t *testing.T) {
+	s := daikintest.NewServer()
+	defer s.Close()
+
+	d := &daikin.Daikin{Address: s.Address(), Policy: &daikin.RequestPolicy{Retries: 2}}
+	tests := []struct {
+		unit     daikin.TemperatureUnit
+		control  map[string]string
+		temp     interface{}
+		humidity interface{}
+		inside   interface{}
+	}{
+		{daikin.Celsius, map[string]string{"mode": "3", "stemp": "22.5"}, 22.5, 0.0, 23.5},
+		{daikin.Fahrenheit, map[string]string{"mode": "3", "stemp": "22.0"}, 72.0, 0.0, 74.0},
+		{daikin.Fahrenheit, map[string]string{"mode": "2", "stemp": "M", "shum": "AUTO"}, "M", "AUTO", 74.0},
+	}
+	for _, tt := range tests {
+		for k, v := range tt.control {
+			s.Set("/aircon/get_control_info", k, v)
+		}
+		d.Invalidate(daikin.SectionControlInfo)
+		state, err := d.Refresh(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := json.Marshal(newStatusJSON(s.Address(), &state.Snapshot, tt.unit))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got struct {
+			Target      string
+			Unit        string
+			BasicInfo   struct{ Name string }
+			ControlInfo struct {
+				Temperature interface{}
+				Humidity    interface{}
+			}
+			SensorInfo struct{ HomeTemperature interface{} }
+		}
+		if err := json.Unmarshal(out, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Target != s.Address() || got.Unit != tt.unit.Symbol() || got.BasicInfo.Name != "Fake" ||
+			got.ControlInfo.Temperature != tt.temp || got.ControlInfo.Humidity != tt.humidity ||
+			got.SensorInfo.HomeTemperature != tt.inside {
+			t.Errorf("%s: got %s", tt.unit.Symbol(), out)
+		}
+	}
+}
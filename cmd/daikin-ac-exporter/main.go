@@ -15,18 +15,17 @@
 package main
 
 import (
+	"context"
 	"errors"
-	"fmt"
-	"io/fs"
-        "io/ioutil"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 	"net/http"
 
-	"gopkg.in/yaml.v3"
-
 	"github.com/spf13/cobra"
+	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/config"
 	log "github.com/thkukuk/mqtt-exporter/pkg/logger"
 	"github.com/prometheus/client_golang/prometheus"
         "github.com/prometheus/client_golang/prometheus/promhttp"
@@ -36,12 +35,6 @@ const (
 	defListen = ":9071"
 )
 
-type ConfigType struct {
-        Address string `yaml:"address,omitempty"`
-        Listen  string `yaml:"listen"`
-	Verbose bool   `yaml:"verbose"`
-}
-
 var (
         Version = "unreleased"
         Quiet   = false
@@ -50,27 +43,6 @@ var (
         address string
 )
 
-func read_yaml_config(conffile string) (ConfigType, error) {
-
-        var config ConfigType
-
-        file, err := ioutil.ReadFile(conffile)
-        if err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
-			return config, nil
-		} else {
-			return config, fmt.Errorf("Cannot read %q: %v", conffile, err)
-		}
-        }
-        err = yaml.Unmarshal(file, &config)
-        if err != nil {
-                return config, fmt.Errorf("Unmarshal error: %v", err)
-        }
-
-        return config, nil
-}
-
-
 func main() {
 	// daikinAcExporterCmd represents the daikin-ac-exporter command
 	daikinAcExporterCmd := &cobra.Command{
@@ -100,7 +72,7 @@ func runDaikinAcExporterCmd(cmd *cobra.Command, args []string) {
 	if !Quiet {
 		log.Infof("Read yaml config %q\n", configFile)
 	}
-	config, err := read_yaml_config(configFile)
+	conf, err := config.Load(configFile)
 	if err != nil {
 		log.Fatalf("Could not load config: %v", err)
 	}
@@ -121,26 +93,54 @@ func runDaikinAcExporterCmd(cmd *cobra.Command, args []string) {
                 os.Exit(0)
         }()
 
-	if len(config.Listen) == 0 {
-		config.Listen = defListen
+	if len(conf.Listen) == 0 {
+		conf.Listen = defListen
         }
 
-	if len(address) == 0 && len(config.Address) > 0 {
-		address = config.Address
-	}
-
-	if config.Verbose {
+	if conf.Verbose {
 		Verbose = true
 	}
 
-	collector := newCollector(config)
+	collector := newCollector(conf)
         prometheus.MustRegister(collector)
+
+	if conf.ClockSync > 0 {
+		go syncClock(collector.Devices, conf.ClockSync, conf.ClockZone)
+	}
+	if conf.Discovery.Interval > 0 {
+		go collector.discoverEvery(conf.Discovery.Interval)
+	}
+
         http.Handle("/metrics", promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
                 // XXX ErrorLog: log,
         }))
 
 	if !Quiet {
-                log.Infof("Starting http server on %s", config.Listen)
+                log.Infof("Starting http server on %s", conf.Listen)
         }
-        log.Fatal(http.ListenAndServe(config.Listen, nil))
+        log.Fatal(http.ListenAndServe(conf.Listen, nil))
+}
+
+// syncClock sets the clock of all devices to the local time, first
+// at startup and afterwards every interval.
+func syncClock(dn *daikin.DaikinNetwork, interval time.Duration, zone string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for target, d := range dn.List() {
+			err := d.SetClock(context.Background(), time.Now(), zone)
+			if errors.Is(err, daikin.ErrNotSupported) {
+				continue
+			}
+			if err != nil {
+				log.Errorf("%s: clock sync failed: %v", target, err)
+				continue
+			}
+			if Verbose {
+				log.Debugf("%s: clock synchronized", target)
+			}
+		}
+		<-ticker.C
+	}
 }
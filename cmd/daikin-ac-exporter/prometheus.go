@@ -15,8 +15,15 @@
 package main
 
 import (
+	"context"
+	"encoding"
+	"strconv"
+	"sync"
+	"time"
+
 	log "github.com/thkukuk/mqtt-exporter/pkg/logger"
 	"github.com/thkukuk/daikin-gomod/api"
+	"github.com/thkukuk/daikin-gomod/config"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -425,6 +432,18 @@ var (
                 []string{"target"}, nil,
         )
 
+        week_power = prometheus.NewDesc(
+                prometheus.BuildFQName(namespace, "", "week_power"),
+                "power info, power consumption of the last days (day=0 is today)",
+                []string{"target", "day"}, nil,
+        )
+
+        zone_pow = prometheus.NewDesc(
+                prometheus.BuildFQName(namespace, "", "zone_pow"),
+                "zone info, zone open (zone_onoff)",
+                []string{"target", "zone", "name"}, nil,
+        )
+
         curr_day_cool = prometheus.NewDesc(
                 prometheus.BuildFQName(namespace, "", "curr_day_cool"),
                 "power info, power consumption cooling",
@@ -434,26 +453,138 @@ var (
 
 type Collector struct {
 	Devices *daikin.DaikinNetwork
+	// Inventory is nil if no inventory file is configured
+	Inventory *daikin.Inventory
+	// labelNames are the names of the labels of the configured
+	// devices, exported with device_labels
+	labelNames []string
+	labelsDesc *prometheus.Desc
+
+	// discovering is held while Discover runs
+	discovering sync.Mutex
+	// lastDiscover is the time of the last discovery, guarded by
+	// discovering
+	lastDiscover time.Time
 }
 
-func newCollector(config ConfigType) *Collector {
+// rediscoverDelay is the minimum time between two discoveries started
+// because a unit configured by MAC address or alias did not reply.
+const rediscoverDelay = time.Minute
+
+func newCollector(conf *config.Config) *Collector {
 	if Verbose {
 		log.Debug("Creating prometheus collector...")
 	}
 
+	var inv *daikin.Inventory
+	if len(conf.Inventory) > 0 {
+		var err error
+		if inv, err = daikin.LoadInventory(conf.Inventory); err != nil {
+			log.Fatalf("Could not load inventory: %v", err)
+		}
+	}
+
 	// XXX return error, don't abort
-	d, err := daikin.NewNetwork(daikin.DebugOption(Verbose),
-				    daikin.AddressOption(address))
+	opts := append(conf.NetworkOptions(address),
+		daikin.DebugOption(Verbose),
+		daikin.InventoryOption(inv),
+		daikin.CacheOption(conf.Polling.TTL(daikin.DefaultCacheTTL)))
+	d, err := daikin.NewNetwork(opts...)
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
         if err = d.Discover(); err != nil {
-		log.Fatalf("Discover Error: %v", err)
+		if len(d.Devices) == 0 {
+			log.Fatalf("Discover Error: %v", err)
+		}
+		log.Warnf("Discover Error: %v", err)
         }
+	c := &Collector{
+		Devices:      d,
+		Inventory:    inv,
+		labelNames:   conf.LabelNames(),
+		lastDiscover: time.Now(),
+	}
+	if len(c.labelNames) > 0 {
+		c.labelsDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "device_labels"),
+			"user-defined labels of the configured devices",
+			append([]string{"target"}, c.labelNames...), nil,
+		)
+	}
+	c.saveInventory()
+	return c
+}
+
+// discover searches the units again, so that units configured by MAC
+// address or alias are found at their new address. The caller holds
+// discovering.
+func (c *Collector) discover() {
+	if err := c.Devices.Discover(); err != nil {
+		log.Warnf("Discover Error: %v", err)
+	}
+	c.lastDiscover = time.Now()
+	c.saveInventory()
+}
+
+// rediscover starts discover in the background, unless it is running
+// or ran less than rediscoverDelay ago.
+func (c *Collector) rediscover() {
+	if !c.discovering.TryLock() {
+		return
+	}
+	if time.Since(c.lastDiscover) < rediscoverDelay {
+		c.discovering.Unlock()
+		return
+	}
+	go func() {
+		defer c.discovering.Unlock()
+		c.discover()
+	}()
+}
+
+// discoverEvery runs discover every interval.
+func (c *Collector) discoverEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-	return &Collector{
-		Devices: d,
+	for range ticker.C {
+		c.discovering.Lock()
+		c.discover()
+		c.discovering.Unlock()
+	}
+}
+
+func (c *Collector) saveInventory() {
+	if c.Inventory == nil {
+		return
+	}
+	if err := c.Inventory.Save(); err != nil {
+		log.Errorf("Could not save inventory: %v", err)
+	}
+}
+
+// label returns the target label of the unit at address. It is the
+// name of the configured device or, with an inventory, the alias or the
+// MAC address, which do not change with the address assigned by DHCP.
+func (c *Collector) label(address string, s *daikin.Snapshot) string {
+	if c.Inventory != nil && c.Inventory.Update(address, s.BasicInfo, s.ModelInfo) {
+		c.saveInventory()
+	}
+	if settings, ok := c.Devices.Settings(address); ok && len(settings.Alias) > 0 {
+		return settings.Alias
+	}
+	if c.Inventory == nil {
+		return address
 	}
+	if s.BasicInfo == nil || len(s.BasicInfo.MAC()) == 0 {
+		return address
+	}
+	e, ok := c.Inventory.Lookup(s.BasicInfo.MAC())
+	if ok && len(e.Alias) > 0 {
+		return e.Alias
+	}
+	return s.BasicInfo.MAC()
 }
 
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
@@ -525,50 +656,138 @@ func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
         ch <- ndfdh
 	ch <- curr_day_heat
 	ch <- curr_day_cool
+	ch <- week_power
+	ch <- zone_pow
+	if c.labelsDesc != nil {
+		ch <- c.labelsDesc
+	}
+}
+
+// sampleLabels sends the user-defined labels of the unit at address.
+func (c *Collector) sampleLabels(ch chan<- prometheus.Metric, address string, target string) {
+	if c.labelsDesc == nil {
+		return
+	}
+	settings, _ := c.Devices.Settings(address)
+	if len(settings.Labels) == 0 {
+		return
+	}
+	values := []string{target}
+	for _, l := range c.labelNames {
+		values = append(values, settings.Labels[l])
+	}
+	ch <- prometheus.MustNewConstMetric(c.labelsDesc, prometheus.GaugeValue, 1, values...)
+}
+
+// optional is a value, which the unit may report as not available.
+type optional interface {
+	IsSet() bool
+	Float64() float64
+}
+
+// sample sends the metric for v, missing values are skipped.
+func sample(ch chan<- prometheus.Metric, desc *prometheus.Desc, v optional, labels ...string) {
+	if !v.IsSet() {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v.Float64(), labels...)
+}
+
+// extraValue is a value reported by the unit without a field of its
+// own, like the louvre settings memorized for the mode (b_f_dir).
+type extraValue interface {
+	encoding.TextUnmarshaler
+	Float64() float64
 }
 
+// sampleExtra sends the metric for the value of key in extra, if the
+// unit reported it.
+func sampleExtra(ch chan<- prometheus.Metric, desc *prometheus.Desc, extra map[string]string, key string, v extraValue, labels ...string) {
+	s, ok := extra[key]
+	if !ok {
+		return
+	}
+	if err := v.UnmarshalText([]byte(s)); err != nil {
+		log.Debugf("%s: %v", key, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v.Float64(), labels...)
+}
+
+// fetched returns true if the section of st was fetched without error.
+// Sections which failed keep the values of the previous fetch, they are
+// not exported.
+func fetched(st *daikin.State, sec daikin.Section) bool {
+	return st.Err(sec) == nil && !st.Fetched(sec).IsZero()
+}
+
+// Collect exports the sections of every device, which could be fetched.
+// A failing section does not stop the export of the other sections.
 func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 
-        for target, d := range c.Devices.Devices {
-
-                if err := d.GetBasicInfo(); err != nil {
-                        log.Error(err)
-                        continue
-                }
-                if err := d.GetControlInfo(); err != nil {
-                        log.Error(err)
-                        continue
-                }
-                if err := d.GetSensorInfo(); err != nil {
-                        log.Error(err)
-                        continue
-                }
-                if err := d.GetPowerInfo(); err != nil {
-                        log.Error(err)
-                        continue
-                }
+	ctx := context.Background()
+	for address, dev := range c.Devices.List() {
+
+		d, err := dev.Refresh(ctx)
+		if err != nil {
+			log.Errorf("%s: %v", address, err)
+			if c.Devices.Resolved(address) {
+				// the unit may have got a new address
+				c.rediscover()
+			}
+		}
+		if !fetched(&d, daikin.SectionBasicInfo) {
+			// the other sections are only fetched after the basic info
+			continue
+		}
 		if Verbose {
-			log.Debugf("Current %s:\n%s\n\n", target, d)
+			log.Debugf("Current %s:\n%s\n\n", address, &d)
 		}
+		target := c.label(address, &d.Snapshot)
+		c.sampleLabels(ch, address, target)
 
 		// Device Info
-		ch <- prometheus.MustNewConstMetric(device_info, prometheus.GaugeValue, 0, target, d.BasicInfo.Type.String(), d.BasicInfo.Name.String(), d.BasicInfo.Version.String(), d.BasicInfo.Revision.String())
+		if b := d.BasicInfo; b != nil && fetched(&d, daikin.SectionBasicInfo) {
+			ch <- prometheus.MustNewConstMetric(device_info, prometheus.GaugeValue, 0, target, b.Type.String(), b.Name.String(), b.Version.String(), b.Revision.String())
+		}
 
 		// Sensor Info
-		ch <- prometheus.MustNewConstMetric(htemp, prometheus.GaugeValue, d.SensorInfo.HomeTemperature.Float64(), target)
-		ch <- prometheus.MustNewConstMetric(hhum, prometheus.GaugeValue, d.SensorInfo.Humidity.Float64(), target)
-		ch <- prometheus.MustNewConstMetric(otemp, prometheus.GaugeValue, d.SensorInfo.OutsideTemperature.Float64(), target)
+		if s := d.SensorInfo; s != nil && fetched(&d, daikin.SectionSensorInfo) {
+			sample(ch, htemp, &s.HomeTemperature, target)
+			sample(ch, hhum, &s.Humidity, target)
+			sample(ch, otemp, &s.OutsideTemperature, target)
+		}
 
 		// Control Info
-		ch <- prometheus.MustNewConstMetric(pow, prometheus.GaugeValue, d.ControlInfo.Power.Float64(), target)
-		ch <- prometheus.MustNewConstMetric(mode, prometheus.GaugeValue, d.ControlInfo.Mode.Float64(), target)
-		ch <- prometheus.MustNewConstMetric(stemp, prometheus.GaugeValue, d.ControlInfo.Temperature.Float64(), target)
-		ch <- prometheus.MustNewConstMetric(shum, prometheus.GaugeValue, d.ControlInfo.Humidity.Float64(), target)
-		ch <- prometheus.MustNewConstMetric(f_rate, prometheus.GaugeValue, d.ControlInfo.Fan.Float64(), target)
-		ch <- prometheus.MustNewConstMetric(f_dir, prometheus.GaugeValue, d.ControlInfo.FanDir.Float64(), target)
+		if ci := d.ControlInfo; ci != nil && fetched(&d, daikin.SectionControlInfo) {
+			ch <- prometheus.MustNewConstMetric(pow, prometheus.GaugeValue, ci.Power.Float64(), target)
+			ch <- prometheus.MustNewConstMetric(mode, prometheus.GaugeValue, ci.Mode.Float64(), target)
+			sample(ch, stemp, &ci.Temperature, target)
+			sample(ch, shum, &ci.Humidity, target)
+			ch <- prometheus.MustNewConstMetric(f_rate, prometheus.GaugeValue, ci.Fan.Float64(), target)
+			ch <- prometheus.MustNewConstMetric(f_dir, prometheus.GaugeValue, ci.FanDir.Float64(), target)
+			sample(ch, f_dir_ud, &ci.FanDirUD, target)
+			sample(ch, f_dir_lr, &ci.FanDirLR, target)
+			sampleExtra(ch, b_f_dir, ci.Extra, "b_f_dir", new(daikin.FanDir), target)
+			sampleExtra(ch, b_f_dir_ud, ci.Extra, "b_f_dir_ud", new(daikin.Swing), target)
+			sampleExtra(ch, b_f_dir_lr, ci.Extra, "b_f_dir_lr", new(daikin.Swing), target)
+		}
 
 		// Power Info
-		ch <- prometheus.MustNewConstMetric(curr_day_cool, prometheus.GaugeValue, d.PowerInfo.DayCool.Float64(), target)
-		ch <- prometheus.MustNewConstMetric(curr_day_heat, prometheus.GaugeValue, d.PowerInfo.DayHeat.Float64(), target)
+		if d.PowerInfo != nil && fetched(&d, daikin.SectionPowerInfo) {
+			sample(ch, curr_day_cool, &d.PowerInfo.DayCool, target)
+			sample(ch, curr_day_heat, &d.PowerInfo.DayHeat, target)
+			for i := range d.PowerInfo.Week {
+				day := len(d.PowerInfo.Week) - 1 - i
+				sample(ch, week_power, &d.PowerInfo.Week[i], target, strconv.Itoa(day))
+			}
+		}
+
+		// Zone Info
+		if d.Zones != nil && fetched(&d, daikin.SectionZones) {
+			for i, z := range d.Zones.Zones {
+				ch <- prometheus.MustNewConstMetric(zone_pow, prometheus.GaugeValue, z.Power.Float64(), target, strconv.Itoa(i+1), z.Name)
+			}
+		}
 	}
 }
@@ -0,0 +1,547 @@
+// Package config loads the configuration file shared by daikin-ac-ctrl
+// and daikin-ac-exporter.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/thkukuk/daikin-gomod/api"
+)
+
+// Config is the content of the configuration file. All settings are
+// optional.
+type Config struct {
+	// Address is a single unit to use instead of discovery, an
+	// address, MAC address or alias of the inventory.
+	Address string `yaml:"address,omitempty"`
+	// Devices are the configured units.
+	Devices []Device `yaml:"devices,omitempty"`
+	// Discovery configures the search for units on the local network.
+	Discovery Discovery `yaml:"discovery,omitempty"`
+	// Polling are the intervals the exporter refetches the state of
+	// the units.
+	Polling Polling `yaml:"polling,omitempty"`
+	// Requests configures the requests to the units.
+	Requests Requests `yaml:"requests,omitempty"`
+	// Concurrency is the maximum number of units contacted at the
+	// same time.
+	Concurrency int `yaml:"concurrency,omitempty"`
+	// Credentials of units not listed in Devices, keyed by address
+	// or MAC address.
+	Credentials map[string]Credentials `yaml:"credentials,omitempty"`
+	// Groups of units for selectors, the members are addresses, MAC
+	// addresses or names.
+	Groups map[string][]string `yaml:"groups,omitempty"`
+	// Inventory is the file recording the units by MAC address.
+	Inventory string `yaml:"inventory,omitempty"`
+
+	// Unit is the display unit of temperatures of daikin-ac-ctrl,
+	// "C" or "F".
+	Unit string `yaml:"unit,omitempty"`
+
+	// Listen is the address of the HTTP server of daikin-ac-exporter.
+	Listen  string `yaml:"listen,omitempty"`
+	Verbose bool   `yaml:"verbose,omitempty"`
+	// ClockSync is the interval to set the clock of the units, e.g.
+	// 24h. Zero disables the clock synchronization.
+	ClockSync time.Duration `yaml:"clock_sync,omitempty"`
+	// ClockZone is the optional time zone index to configure on the
+	// units, as reported by the units, e.g. 54.
+	ClockZone string `yaml:"clock_zone,omitempty"`
+}
+
+// Device is a configured unit.
+type Device struct {
+	// Name identifies the unit in selectors and is the target label
+	// of its metrics.
+	Name string `yaml:"name"`
+	// Address or MAC is required. A MAC address is resolved to the
+	// current address of the unit by discovery or the inventory.
+	Address string `yaml:"address,omitempty"`
+	MAC     string `yaml:"mac,omitempty"`
+	// Labels are added to the metrics of the unit.
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Credentials *Credentials      `yaml:"credentials,omitempty"`
+	Groups      []string          `yaml:"groups,omitempty"`
+	// Polling and Requests override the global settings.
+	Polling  *Polling  `yaml:"polling,omitempty"`
+	Requests *Requests `yaml:"requests,omitempty"`
+}
+
+// Credentials are the access credentials of adapters requiring HTTPS or
+// a local password.
+type Credentials struct {
+	UUID     string `yaml:"uuid,omitempty"`
+	Key      string `yaml:"key,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	Secure   bool   `yaml:"secure,omitempty"`
+}
+
+// Discovery configures the search for units on the local network.
+type Discovery struct {
+	// Enabled defaults to true if no units are configured. With
+	// configured units, it adds the units found.
+	Enabled *bool `yaml:"enabled,omitempty"`
+	// Interface is the network interface to search on, default all.
+	Interface string `yaml:"interface,omitempty"`
+	// Polls is the number of broadcasts, default 1.
+	Polls int `yaml:"polls,omitempty"`
+	// Timeout is the time to wait for replies, default 1s.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Interval is the interval the exporter searches again, default
+	// only at startup. Units configured by MAC address or alias are
+	// also searched again if they do not reply.
+	Interval time.Duration `yaml:"interval,omitempty"`
+}
+
+// Polling are the intervals to refetch the sections of the state of a
+// unit, zero for the defaults of daikin.DefaultCacheTTL.
+type Polling struct {
+	BasicInfo   time.Duration `yaml:"basic_info,omitempty"`
+	ControlInfo time.Duration `yaml:"control_info,omitempty"`
+	SensorInfo  time.Duration `yaml:"sensor_info,omitempty"`
+	PowerInfo   time.Duration `yaml:"power_info,omitempty"`
+	Zones       time.Duration `yaml:"zones,omitempty"`
+}
+
+// Requests configures the requests to a unit, zero for the defaults of
+// daikin.DefaultRequestPolicy.
+type Requests struct {
+	MinGap     time.Duration `yaml:"min_gap,omitempty"`
+	Retries    *int          `yaml:"retries,omitempty"`
+	Backoff    time.Duration `yaml:"backoff,omitempty"`
+	MaxBackoff time.Duration `yaml:"max_backoff,omitempty"`
+}
+
+// Load reads and validates the configuration file. A missing file
+// returns an empty configuration.
+func Load(file string) (*Config, error) {
+	c := &Config{}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read %q: %v", file, err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: invalid configuration:\n  %s", file,
+			strings.ReplaceAll(err.Error(), "\n", "\n  "))
+	}
+	return c, nil
+}
+
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabels are the labels of the metrics of daikin-ac-exporter.
+var reservedLabels = []string{"target", "type", "name", "version", "revision", "day", "zone"}
+
+// Validate checks the configuration. All problems are returned joined,
+// one per line.
+func (c *Config) Validate() error {
+	var errs []error
+	errorf := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if len(c.Address) > 0 && len(c.Devices) > 0 {
+		errorf("address and devices are exclusive, add the unit to devices")
+	}
+	names := map[string]int{}
+	keys := map[string]int{}
+	for i, d := range c.Devices {
+		where := fmt.Sprintf("devices[%d]", i)
+		if len(d.Name) > 0 {
+			where = fmt.Sprintf("devices[%d] (%s)", i, d.Name)
+		}
+		switch {
+		case len(d.Name) == 0:
+			errorf("%s: name is missing", where)
+		case strings.ContainsAny(d.Name, ",:") || d.Name == "all":
+			errorf("%s: name must not be \"all\" or contain ',' or ':'", where)
+		default:
+			if j, ok := names[strings.ToLower(d.Name)]; ok {
+				errorf("%s: name is already used by devices[%d]", where, j)
+			}
+			names[strings.ToLower(d.Name)] = i
+		}
+
+		key := d.Address
+		switch {
+		case len(d.Address) == 0 && len(d.MAC) == 0:
+			errorf("%s: address or mac is missing", where)
+		case len(d.Address) > 0 && len(d.MAC) > 0:
+			errorf("%s: address and mac are exclusive", where)
+		case len(d.MAC) > 0:
+			key = daikin.NormalizeMAC(d.MAC)
+			if len(key) == 0 {
+				errorf("%s: invalid mac %q, expected 12 hex digits like A4:CB:12:34:56:78", where, d.MAC)
+			}
+		case strings.Contains(d.Address, "/"):
+			errorf("%s: invalid address %q, expected a host name or IP address without scheme", where, d.Address)
+		}
+		if len(key) > 0 {
+			if j, ok := keys[key]; ok {
+				errorf("%s: unit is already configured as devices[%d]", where, j)
+			}
+			keys[key] = i
+		}
+
+		labels := make([]string, 0, len(d.Labels))
+		for l := range d.Labels {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+		for _, l := range labels {
+			if !labelName.MatchString(l) {
+				errorf("%s: invalid label name %q", where, l)
+			} else if contains(reservedLabels, l) {
+				errorf("%s: label %q is used by the metrics", where, l)
+			}
+		}
+		for _, g := range d.Groups {
+			if len(g) == 0 || strings.ContainsAny(g, ",:") {
+				errorf("%s: invalid group %q", where, g)
+			}
+		}
+		if d.Polling != nil {
+			errs = append(errs, d.Polling.validate(where+": polling")...)
+		}
+		if d.Requests != nil {
+			errs = append(errs, d.Requests.validate(where+": requests")...)
+		}
+	}
+
+	for g := range c.Groups {
+		if len(g) == 0 || strings.ContainsAny(g, ",:") {
+			errorf("groups: invalid group %q", g)
+		}
+	}
+	if c.Discovery.Polls < 0 {
+		errorf("discovery: polls must not be negative")
+	}
+	if c.Discovery.Timeout < 0 {
+		errorf("discovery: timeout must not be negative")
+	}
+	if c.Discovery.Interval < 0 {
+		errorf("discovery: interval must not be negative")
+	}
+	errs = append(errs, c.Polling.validate("polling")...)
+	errs = append(errs, c.Requests.validate("requests")...)
+	if c.Concurrency < 0 {
+		errorf("concurrency must not be negative")
+	}
+	if _, err := daikin.ParseTemperatureUnit(c.Unit); err != nil {
+		errorf("unit: %v", err)
+	}
+	if c.ClockSync < 0 {
+		errorf("clock_sync must not be negative")
+	}
+	if err := daikin.CheckClockZone(c.ClockZone); err != nil {
+		errorf("clock_zone: %v", err)
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Polling) validate(where string) []error {
+	var errs []error
+	for _, d := range []struct {
+		key string
+		v   time.Duration
+	}{
+		{"basic_info", p.BasicInfo},
+		{"control_info", p.ControlInfo},
+		{"sensor_info", p.SensorInfo},
+		{"power_info", p.PowerInfo},
+		{"zones", p.Zones},
+	} {
+		if d.v < 0 {
+			errs = append(errs, fmt.Errorf("%s: %s must not be negative", where, d.key))
+		}
+	}
+	return errs
+}
+
+func (r *Requests) validate(where string) []error {
+	var errs []error
+	if r.MinGap < 0 || r.Backoff < 0 || r.MaxBackoff < 0 {
+		errs = append(errs, fmt.Errorf("%s: durations must not be negative", where))
+	}
+	if r.Retries != nil && *r.Retries < 0 {
+		errs = append(errs, fmt.Errorf("%s: retries must not be negative", where))
+	}
+	if r.MaxBackoff > 0 && r.Backoff > r.MaxBackoff {
+		errs = append(errs, fmt.Errorf("%s: backoff exceeds max_backoff", where))
+	}
+	return errs
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// TTL returns the cache TTLs of the polling intervals, defaults are
+// taken from base.
+func (p *Polling) TTL(base daikin.CacheTTL) daikin.CacheTTL {
+	ttl := base
+	set := func(dst *time.Duration, v time.Duration) {
+		if v > 0 {
+			*dst = v
+		}
+	}
+	set(&ttl.BasicInfo, p.BasicInfo)
+	set(&ttl.ControlInfo, p.ControlInfo)
+	set(&ttl.SensorInfo, p.SensorInfo)
+	set(&ttl.PowerInfo, p.PowerInfo)
+	set(&ttl.Zones, p.Zones)
+	return ttl
+}
+
+// Policy returns the request policy, defaults are taken from base.
+func (r *Requests) Policy(base daikin.RequestPolicy) daikin.RequestPolicy {
+	p := base
+	if r.MinGap > 0 {
+		p.MinGap = r.MinGap
+	}
+	if r.Retries != nil {
+		p.Retries = *r.Retries
+	}
+	if r.Backoff > 0 {
+		p.Backoff = r.Backoff
+	}
+	if r.MaxBackoff > 0 {
+		p.MaxBackoff = r.MaxBackoff
+	}
+	return p
+}
+
+func (c Credentials) credentials() daikin.Credentials {
+	return daikin.Credentials{
+		UUID:     c.UUID,
+		Key:      c.Key,
+		Password: c.Password,
+		Secure:   c.Secure,
+	}
+}
+
+// Key returns the address or MAC address of the unit.
+func (d *Device) Key() string {
+	if len(d.Address) > 0 {
+		return d.Address
+	}
+	return d.MAC
+}
+
+// NetworkOptions returns the options for daikin.NewNetwork. address
+// overrides the configured units, e.g. from the command line. The
+// exporter adds daikin.CacheOption(c.Polling.TTL(...)), per-device
+// polling intervals are applied then.
+func (c *Config) NetworkOptions(address string) []daikin.Option {
+	opts := []daikin.Option{daikin.InterfaceOption(c.Discovery.Interface)}
+
+	creds := map[string]daikin.Credentials{}
+	for k, cred := range c.Credentials {
+		creds[k] = cred.credentials()
+	}
+	opts = append(opts, daikin.CredentialsOption(creds))
+	if len(c.Groups) > 0 {
+		opts = append(opts, daikin.GroupsOption(c.Groups))
+	}
+	if c.Concurrency > 0 {
+		opts = append(opts, daikin.ConcurrencyOption(c.Concurrency))
+	}
+	global := c.Requests.Policy(daikin.DefaultRequestPolicy)
+	if c.Requests != (Requests{}) {
+		opts = append(opts, daikin.PolicyOption(global))
+	}
+
+	if len(address) == 0 {
+		address = c.Address
+	}
+	if len(address) > 0 {
+		return append(opts, daikin.AddressOption(address))
+	}
+
+	for _, d := range c.Devices {
+		opts = append(opts, daikin.AddressOption(d.Key()))
+		s := daikin.DeviceSettings{
+			Alias:  d.Name,
+			Groups: d.Groups,
+			Labels: d.Labels,
+		}
+		if d.Credentials != nil {
+			cred := d.Credentials.credentials()
+			s.Credentials = &cred
+		}
+		if d.Requests != nil {
+			p := d.Requests.Policy(global)
+			s.Policy = &p
+		}
+		if d.Polling != nil {
+			ttl := d.Polling.TTL(c.Polling.TTL(daikin.DefaultCacheTTL))
+			s.Cache = &ttl
+		}
+		opts = append(opts, daikin.DeviceOption(d.Key(), s))
+	}
+
+	enabled := len(c.Devices) == 0
+	if c.Discovery.Enabled != nil {
+		enabled = *c.Discovery.Enabled
+	}
+	polls := 0
+	if enabled {
+		polls = c.Discovery.Polls
+		if polls == 0 {
+			polls = 1
+		}
+	}
+	return append(opts, daikin.DiscoveryOption(polls, c.Discovery.Timeout))
+}
+
+// LabelNames returns the names of the labels of all devices, sorted.
+func (c *Config) LabelNames() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, d := range c.Devices {
+		for l := range d.Labels {
+			if !seen[l] {
+				seen[l] = true
+				names = append(names, l)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SaveCredentials stores the credentials for the unit with the address
+// or MAC address key in the configuration file, keeping all other
+// settings and comments. If the unit is listed in devices, they are
+// stored there, else in credentials.
+func SaveCredentials(file string, key string, cred Credentials) error {
+	var doc yaml.Node
+
+	data, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("Cannot read %q: %v", file, err)
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: expected a mapping", file)
+	}
+
+	var value yaml.Node
+	if err := value.Encode(cred); err != nil {
+		return err
+	}
+	if dev := deviceNode(root, key); dev != nil {
+		*mappingValue(dev, "credentials") = value
+	} else {
+		creds := mappingValue(root, "credentials")
+		if creds.Kind != yaml.MappingNode {
+			*creds = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		*mappingValue(creds, key) = value
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	enc.Close()
+	return os.WriteFile(file, out.Bytes(), 0600)
+}
+
+// deviceNode returns the node of the entry of devices with the address
+// or MAC address key, nil if there is none.
+func deviceNode(root *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "devices" || root.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, dev := range root.Content[i+1].Content {
+			var d Device
+			if dev.Kind != yaml.MappingNode || dev.Decode(&d) != nil {
+				continue
+			}
+			if d.Address == key || (len(d.MAC) > 0 && daikin.NormalizeMAC(d.MAC) == daikin.NormalizeMAC(key)) {
+				return dev
+			}
+		}
+	}
+	return nil
+}
+
+// mappingValue returns the value node of key in the mapping node m, the
+// key is added if it does not exist.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	k := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	v := &yaml.Node{}
+	m.Content = append(m.Content, k, v)
+	return v
+}
+
+// Device returns the configured unit with the name, nil if there is
+// none.
+func (c *Config) Device(name string) *Device {
+	for i := range c.Devices {
+		if strings.EqualFold(c.Devices[i].Name, name) {
+			return &c.Devices[i]
+		}
+	}
+	return nil
+}
+
+// CredentialsOf returns the credentials of the unit with the address
+// or MAC address key.
+func (c *Config) CredentialsOf(key string) daikin.Credentials {
+	mac := daikin.NormalizeMAC(key)
+	for _, d := range c.Devices {
+		if d.Credentials != nil && (d.Address == key || (len(mac) > 0 && daikin.NormalizeMAC(d.MAC) == mac)) {
+			return d.Credentials.credentials()
+		}
+	}
+	for k, cred := range c.Credentials {
+		if k == key || (len(mac) > 0 && daikin.NormalizeMAC(k) == mac) {
+			return cred.credentials()
+		}
+	}
+	return daikin.Credentials{}
+}
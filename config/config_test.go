@@ -0,0 +1,155 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes data to a configuration file in a temporary
+// directory and returns its name.
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoad(t *testing.T) {
+	file := writeConfig(t, `
+devices:
+  - name: living
+    address: 192.168.1.10
+    labels:
+      room: living
+    groups: [downstairs]
+  - name: bedroom
+    mac: a4cb.1234.5678
+    requests:
+      retries: 0
+polling:
+  control_info: 30s
+clock_sync: 24h
+unit: F
+`)
+	c, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Devices) != 2 || c.Polling.ControlInfo != 30*time.Second || c.ClockSync != 24*time.Hour {
+		t.Errorf("got %+v", c)
+	}
+	if d := c.Device("Bedroom"); d == nil || d.Key() != "a4cb.1234.5678" {
+		t.Errorf("bedroom: got %+v", d)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), "none.yaml"))
+	if err != nil || c == nil || len(c.Devices) > 0 {
+		t.Errorf("got %+v, %v", c, err)
+	}
+}
+
+func TestLoadUnknownKey(t *testing.T) {
+	if _, err := Load(writeConfig(t, "adress: 192.168.1.10\n")); err == nil {
+		t.Error("no error for unknown key")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		config string
+		err    string
+	}{
+		{"address: a\ndevices: [{name: x, address: b}]", "address and devices are exclusive"},
+		{"devices: [{address: a}]", "name is missing"},
+		{"devices: [{name: all, address: a}]", `must not be "all"`},
+		{"devices: [{name: a:b, address: a}]", "must not be"},
+		{"devices: [{name: x, address: a}, {name: X, address: b}]", "name is already used by devices[0]"},
+		{"devices: [{name: x}]", "address or mac is missing"},
+		{"devices: [{name: x, address: a, mac: a4cb12345678}]", "address and mac are exclusive"},
+		{"devices: [{name: x, mac: a4cb1234}]", "invalid mac"},
+		{"devices: [{name: x, address: http://a}]", "invalid address"},
+		{"devices: [{name: x, mac: A4:CB:12:34:56:78}, {name: y, mac: a4cb.1234.5678}]", "already configured as devices[0]"},
+		{"devices: [{name: x, address: a, labels: {1x: v}}]", "invalid label name"},
+		{"devices: [{name: x, address: a, labels: {target: v}}]", "used by the metrics"},
+		{"devices: [{name: x, address: a, groups: ['a,b']}]", "invalid group"},
+		{"devices: [{name: x, address: a, polling: {zones: -1s}}]", "polling: zones must not be negative"},
+		{"devices: [{name: x, address: a, requests: {retries: -1}}]", "retries must not be negative"},
+		{"groups: {'a:b': [x]}", "invalid group"},
+		{"discovery: {polls: -1}", "polls must not be negative"},
+		{"discovery: {interval: -1m}", "interval must not be negative"},
+		{"requests: {backoff: 2s, max_backoff: 1s}", "backoff exceeds max_backoff"},
+		{"concurrency: -1", "concurrency must not be negative"},
+		{"unit: K", "unit:"},
+		{"clock_sync: -1h", "clock_sync must not be negative"},
+		{"clock_zone: Europe/Berlin", "clock_zone: invalid time zone"},
+	}
+	for _, tt := range tests {
+		_, err := Load(writeConfig(t, tt.config))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got %v, want %q", tt.config, err, tt.err)
+		}
+	}
+}
+
+func TestCredentialsOf(t *testing.T) {
+	c, err := Load(writeConfig(t, `
+devices:
+  - name: x
+    mac: A4:CB:12:34:56:78
+    credentials: {key: device}
+credentials:
+  192.168.1.20: {key: address}
+  0011.2233.4455: {key: mac}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"a4cb.1234.5678":    "device",
+		"192.168.1.20":      "address",
+		"00-11-22-33-44-55": "mac",
+		"192.168.1.30":      "",
+	} {
+		if got := c.CredentialsOf(key).Key; got != want {
+			t.Errorf("%s: got %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestSaveCredentials(t *testing.T) {
+	file := writeConfig(t, `# units
+devices:
+  - name: x
+    mac: a4cb.1234.5678
+`)
+	if err := SaveCredentials(file, "A4CB12345678", Credentials{Key: "k1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveCredentials(file, "192.168.1.20", Credentials{Key: "k2"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "# units") {
+		t.Errorf("comment lost:\n%s", data)
+	}
+	c, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Devices[0].Credentials; got == nil || got.Key != "k1" {
+		t.Errorf("device credentials: got %+v", got)
+	}
+	if got := c.Credentials["192.168.1.20"].Key; got != "k2" {
+		t.Errorf("credentials: got %q", got)
+	}
+}